@@ -0,0 +1,97 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	accessRulesRecentWindow = 24 * time.Hour
+
+	accessRulesMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_ip_access_rules",
+			Help: "IP access rules applying to the zone by mode",
+		},
+		[]string{"zone_tag", "mode"},
+	)
+
+	accessRulesRecentMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_ip_access_rules_recent",
+			Help: "IP access rules created within IP_ACCESS_RULES_RECENT_WINDOW by mode",
+		},
+		[]string{"zone_tag", "mode"},
+	)
+
+	accessRuleRecentInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_ip_access_rule_recent_info",
+			Help: "IP access rules created within IP_ACCESS_RULES_RECENT_WINDOW, value is the creation time",
+		},
+		[]string{"zone_tag", "rule_id", "mode", "target", "value", "scope"},
+	)
+)
+
+// accessRuleModes are always exported, so a mode without rules reads 0.
+var accessRuleModes = []string{"block", "challenge", "js_challenge", "managed_challenge", "whitelist"}
+
+func init() {
+	cycleRegistry.MustRegister(accessRulesMetric)
+	cycleRegistry.MustRegister(accessRulesRecentMetric)
+	cycleRegistry.MustRegister(accessRuleRecentInfo)
+
+	registerCollector(collector{
+		name:  "ip_access_rules",
+		scope: "Firewall Services",
+		zone:  fetchAccessRules,
+	})
+}
+
+func fetchAccessRules(zone Zone) error {
+	// the zone endpoint also lists the account and user level rules that
+	// apply to the zone, scope tells them apart
+	rules, err := cfGetAll[struct {
+		ID            string    `json:"id"`
+		Mode          string    `json:"mode"`
+		CreatedOn     time.Time `json:"created_on"`
+		Configuration struct {
+			Target string `json:"target"`
+			Value  string `json:"value"`
+		} `json:"configuration"`
+		Scope struct {
+			Type string `json:"type"`
+		} `json:"scope"`
+	}]("/zones/"+zone.ID+"/firewall/access_rules/rules", 1000)
+	if err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	accessRulesMetric.DeletePartialMatch(byZone)
+	accessRulesRecentMetric.DeletePartialMatch(byZone)
+	accessRuleRecentInfo.DeletePartialMatch(byZone)
+
+	total := map[string]int{}
+	recent := map[string]int{}
+	for _, mode := range accessRuleModes {
+		total[mode] = 0
+		recent[mode] = 0
+	}
+	since := time.Now().Add(-accessRulesRecentWindow)
+	for _, r := range rules {
+		total[r.Mode]++
+		if r.CreatedOn.After(since) {
+			recent[r.Mode]++
+			accessRuleRecentInfo.WithLabelValues(zone.Tag, r.ID, r.Mode, r.Configuration.Target, r.Configuration.Value, r.Scope.Type).Set(float64(r.CreatedOn.Unix()))
+		}
+	}
+	for mode, n := range total {
+		accessRulesMetric.WithLabelValues(zone.Tag, mode).Set(float64(n))
+	}
+	for mode, n := range recent {
+		accessRulesRecentMetric.WithLabelValues(zone.Tag, mode).Set(float64(n))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	accountMembersMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_account_members",
+			Help: "Account members by role and membership status",
+		},
+		[]string{"account", "role", "status"},
+	)
+
+	accountTokensMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_account_api_tokens",
+			Help: "Account-owned API tokens by status",
+		},
+		[]string{"account", "status"},
+	)
+
+	accountTokenAge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_account_api_token_age_seconds",
+			Help: "Time since an active account-owned API token was issued",
+		},
+		[]string{"account", "token_id", "name"},
+	)
+
+	accountTokenLastUsed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_account_api_token_last_used_timestamp_seconds",
+			Help: "Last use of an active account-owned API token, absent if never used",
+		},
+		[]string{"account", "token_id", "name"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(accountMembersMetric)
+	cycleRegistry.MustRegister(accountTokensMetric)
+	cycleRegistry.MustRegister(accountTokenAge)
+	cycleRegistry.MustRegister(accountTokenLastUsed)
+
+	registerCollector(collector{
+		name:    "account_inventory",
+		scope:   "Account Settings",
+		account: fetchAccountInventory,
+	})
+}
+
+func fetchAccountInventory(account Account) error {
+	members, err := cfGetAll[struct {
+		Status string `json:"status"`
+		Roles  []struct {
+			Name string `json:"name"`
+		} `json:"roles"`
+	}]("/accounts/"+account.ID+"/members", 50)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := cfGetAll[struct {
+		ID         string     `json:"id"`
+		Name       string     `json:"name"`
+		Status     string     `json:"status"`
+		IssuedOn   time.Time  `json:"issued_on"`
+		LastUsedOn *time.Time `json:"last_used_on"`
+	}]("/accounts/"+account.ID+"/tokens", 50)
+	if err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	accountMembersMetric.DeletePartialMatch(byAccount)
+	accountTokensMetric.DeletePartialMatch(byAccount)
+	accountTokenAge.DeletePartialMatch(byAccount)
+	accountTokenLastUsed.DeletePartialMatch(byAccount)
+
+	// a member with several roles is counted once per role
+	for _, m := range members {
+		for _, role := range m.Roles {
+			accountMembersMetric.WithLabelValues(account.Name, role.Name, m.Status).Inc()
+		}
+	}
+
+	now := time.Now()
+	for _, t := range tokens {
+		accountTokensMetric.WithLabelValues(account.Name, t.Status).Inc()
+		if t.Status != "active" {
+			continue
+		}
+		accountTokenAge.WithLabelValues(account.Name, t.ID, t.Name).Set(now.Sub(t.IssuedOn).Seconds())
+		if t.LastUsedOn != nil {
+			accountTokenLastUsed.WithLabelValues(account.Name, t.ID, t.Name).Set(float64(t.LastUsedOn.Unix()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// adminToken enables the admin API; requests must send it as a bearer
+// token.
+var adminToken = ""
+
+func adminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+adminToken)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func registerAdminHandlers() {
+	http.HandleFunc("GET /admin/zones", adminAuth(adminListZones))
+	http.HandleFunc("POST /admin/zones/{zone}", adminAuth(adminAddZone))
+	http.HandleFunc("DELETE /admin/zones/{zone}", adminAuth(adminRemoveZone))
+	http.HandleFunc("POST /admin/zones/{zone}/pause", adminAuth(adminPauseZone(true)))
+	http.HandleFunc("POST /admin/zones/{zone}/resume", adminAuth(adminPauseZone(false)))
+	http.HandleFunc("POST /admin/zones/{zone}/refresh", adminAuth(adminRefreshZone))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func findZone(name string) (Zone, bool) {
+	zonesMutex.RLock()
+	defer zonesMutex.RUnlock()
+	for _, zone := range zones {
+		if zone.Tag == name {
+			return zone, true
+		}
+	}
+	return Zone{}, false
+}
+
+func adminListZones(w http.ResponseWriter, r *http.Request) {
+	type zoneInfo struct {
+		Name      string `json:"name"`
+		ID        string `json:"id"`
+		AccountID string `json:"account_id"`
+		Paused    bool   `json:"paused"`
+	}
+	list := []zoneInfo{}
+	zonesMutex.RLock()
+	for _, zone := range zones {
+		list = append(list, zoneInfo{zone.Tag, zone.ID, zone.AccountID, zonePaused(zone)})
+	}
+	zonesMutex.RUnlock()
+	writeJSON(w, list)
+}
+
+func adminAddZone(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("zone")
+	if _, ok := findZone(name); ok {
+		writeJSON(w, map[string]string{"status": "exists"})
+		return
+	}
+	zone, account, err := lookupZone(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stateMutex.Lock()
+	state.AddedZones[name] = true
+	delete(state.RemovedZones, name)
+	err = saveState()
+	stateMutex.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	zonesMutex.Lock()
+	zones = append(zones, zone)
+	accounts = zoneAccounts(zones, append(accounts, account))
+	indexTokens()
+	zonesMutex.Unlock()
+	if redisClient != nil {
+		publishZones()
+	}
+	log.Println("[OK] Zone added:", name)
+	writeJSON(w, map[string]string{"status": "added"})
+}
+
+func adminRemoveZone(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("zone")
+	if _, ok := findZone(name); !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	stateMutex.Lock()
+	state.RemovedZones[name] = true
+	delete(state.AddedZones, name)
+	err := saveState()
+	stateMutex.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	zonesMutex.Lock()
+	oldAccounts := accounts
+	zones = slices.DeleteFunc(slices.Clone(zones), func(z Zone) bool { return z.Tag == name })
+	accounts = zoneAccounts(zones, accounts)
+	indexTokens()
+	remaining := map[string]bool{}
+	for _, account := range accounts {
+		remaining[account.Name] = true
+	}
+	zonesMutex.Unlock()
+	forgetTarget(name, prometheus.Labels{"zone_tag": name})
+	// the zone may have been the last one of its account
+	for _, account := range oldAccounts {
+		if !remaining[account.Name] {
+			forgetTarget("account:"+account.Name, prometheus.Labels{"account": account.Name})
+		}
+	}
+	if redisClient != nil {
+		publishZones()
+	}
+	log.Println("[OK] Zone removed:", name)
+	writeJSON(w, map[string]string{"status": "removed"})
+}
+
+func adminPauseZone(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("zone")
+		if _, ok := findZone(name); !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		stateMutex.Lock()
+		if paused {
+			state.PausedZones[name] = true
+		} else {
+			delete(state.PausedZones, name)
+		}
+		err := saveState()
+		stateMutex.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]bool{"paused": paused})
+	}
+}
+
+// adminRefreshZone runs the enabled zone collectors for one zone right away,
+// also when the zone is paused.
+func adminRefreshZone(w http.ResponseWriter, r *http.Request) {
+	zone, ok := findZone(r.PathValue("zone"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	errs := map[string]string{}
+	for _, c := range collectors {
+		if !enabledCollectors[c.name] || c.zone == nil {
+			continue
+		}
+		if err := collectZone(c, zone); err != nil {
+			errs[c.name] = err.Error()
+		}
+	}
+	// a running cycle publishes the values when it ends
+	if !cycleRunning.Load() {
+		swapCycle()
+	}
+	writeJSON(w, map[string]any{"status": "refreshed", "errors": errs})
+}
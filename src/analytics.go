@@ -0,0 +1,125 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// analyticsRecheckInterval is how often the GraphQL datasets enabled
+	// for a zone are checked; zones lacking a dataset are not polled by the
+	// collectors using it in between. 0 polls every zone regardless.
+	analyticsRecheckInterval = 24 * time.Hour
+
+	zoneDatasets      = map[string]zoneDatasetCheck{}
+	zoneDatasetsMutex = &sync.Mutex{}
+
+	analyticsAvailableMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_analytics_available",
+			Help: "1 if every GraphQL dataset of the enabled collectors is available for the zone, 0 if some are not entitled and their collectors skip the zone",
+		},
+		[]string{"zone_tag"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(analyticsAvailableMetric)
+}
+
+type zoneDatasetCheck struct {
+	checked time.Time
+	missing map[string]bool
+}
+
+// enabledZoneDatasets lists the datasets queried by enabled zone collectors.
+func enabledZoneDatasets() []string {
+	seen := map[string]bool{}
+	datasets := []string{}
+	for _, c := range collectors {
+		if !enabledCollectors[c.name] || c.zone == nil {
+			continue
+		}
+		for _, d := range c.datasets {
+			if !seen[d] {
+				seen[d] = true
+				datasets = append(datasets, d)
+			}
+		}
+	}
+	return datasets
+}
+
+// checkZoneDatasets refreshes the dataset checks older than
+// analyticsRecheckInterval. A failed check keeps the previous result.
+func checkZoneDatasets(active []Zone) {
+	datasets := enabledZoneDatasets()
+	if analyticsRecheckInterval <= 0 || len(datasets) == 0 {
+		return
+	}
+	stale := []Zone{}
+	zoneDatasetsMutex.Lock()
+	for _, zone := range active {
+		if time.Since(zoneDatasets[zone.Tag].checked) >= analyticsRecheckInterval {
+			stale = append(stale, zone)
+		}
+	}
+	zoneDatasetsMutex.Unlock()
+
+	runPool(len(stale), func(i int) {
+		zone := stale[i]
+		missing, err := datasetSettings(datasets, &zone, nil)
+		if err != nil {
+			log.Printf("[!] Ошибка проверки датасетов зоны %s: %v", zone.Tag, err)
+			return
+		}
+		check := zoneDatasetCheck{checked: time.Now(), missing: map[string]bool{}}
+		for _, d := range missing {
+			check.missing[d] = true
+		}
+		zoneDatasetsMutex.Lock()
+		previous, known := zoneDatasets[zone.Tag]
+		zoneDatasets[zone.Tag] = check
+		zoneDatasetsMutex.Unlock()
+
+		analyticsAvailableMetric.WithLabelValues(zone.Tag).Set(boolToFloat(len(missing) == 0))
+		switch {
+		case len(missing) > 0 && (!known || len(previous.missing) != len(missing)):
+			log.Printf("[!] Датасеты недоступны для зоны %s, зона пропускается: %s", zone.Tag, strings.Join(missing, ", "))
+		case len(missing) == 0 && len(previous.missing) > 0:
+			log.Println("[OK] Datasets available again for zone", zone.Tag)
+		}
+	})
+}
+
+// zonesWithDatasets returns the zones for which every dataset of the
+// collector is available, or was not checked yet.
+func zonesWithDatasets(c collector, active []Zone) []Zone {
+	if analyticsRecheckInterval <= 0 || len(c.datasets) == 0 {
+		return active
+	}
+	zoneDatasetsMutex.Lock()
+	defer zoneDatasetsMutex.Unlock()
+	selected := make([]Zone, 0, len(active))
+	for _, zone := range active {
+		available := true
+		for _, d := range c.datasets {
+			available = available && !zoneDatasets[zone.Tag].missing[d]
+		}
+		if available {
+			selected = append(selected, zone)
+		}
+	}
+	return selected
+}
+
+// forgetZoneDatasets is called when the zone's series were removed.
+func forgetZoneDatasets(zoneTag string) {
+	zoneDatasetsMutex.Lock()
+	defer zoneDatasetsMutex.Unlock()
+	delete(zoneDatasets, zoneTag)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	anomalySigma         = 3.0
+	anomalyBaselineHours = 168
+
+	trafficAnomaly = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_traffic_anomaly",
+			Help: "1 if requests of the last complete hour deviate from the baseline by more than ANOMALY_SIGMA",
+		},
+		[]string{"zone_tag", "direction"},
+	)
+
+	trafficZScore = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_traffic_zscore",
+			Help: "Deviation of the last complete hour's requests from the baseline mean, in standard deviations",
+		},
+		[]string{"zone_tag"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(trafficAnomaly)
+	cycleRegistry.MustRegister(trafficZScore)
+
+	registerCollector(collector{
+		name:     "anomaly",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequests1hGroups"},
+		zone:     fetchAnomaly,
+	})
+}
+
+const anomalyQuery = `query ($zoneTag: string!, $filter: ZoneHttpRequests1hGroupsFilter_InputObject!, $limit: uint64!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      httpRequests1hGroups(filter: $filter, limit: $limit, orderBy: [datetime_ASC]) {
+        sum { requests }
+        dimensions { datetime }
+      }
+    }
+  }
+}`
+
+// fetchAnomaly compares the last complete hour with the mean and standard
+// deviation of the ANOMALY_BASELINE_HOURS hours before it. The baseline is
+// read from Cloudflare every cycle, so it survives restarts.
+func fetchAnomaly(zone Zone) error {
+	hours := max(anomalyBaselineHours, 2)
+	current := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	from := current.Add(-time.Duration(hours) * time.Hour)
+
+	z, err := queryZone[struct {
+		HttpRequests1hGroups []struct {
+			Sum struct {
+				Requests float64 `json:"requests"`
+			} `json:"sum"`
+			Dimensions struct {
+				Datetime time.Time `json:"datetime"`
+			} `json:"dimensions"`
+		} `json:"httpRequests1hGroups"`
+	}](zone, anomalyQuery, map[string]any{
+		"filter": zoneFilter(zone, "httpRequests1hGroups", map[string]any{
+			"datetime_geq": from.Format(time.RFC3339),
+			"datetime_lt":  current.Add(time.Hour).Format(time.RFC3339),
+		}),
+		"limit": hours + 1,
+	})
+	if err != nil || z == nil {
+		return err
+	}
+
+	// hours without traffic are missing from the groups and count as zero
+	byHour := map[time.Time]float64{}
+	for _, g := range z.HttpRequests1hGroups {
+		byHour[g.Dimensions.Datetime.UTC()] = g.Sum.Requests
+	}
+	sum, sumSq := 0.0, 0.0
+	for h := from; h.Before(current); h = h.Add(time.Hour) {
+		v := byHour[h]
+		sum += v
+		sumSq += v * v
+	}
+	mean := sum / float64(hours)
+	stddev := math.Sqrt(max(0, sumSq/float64(hours)-mean*mean))
+
+	trafficAnomaly.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag})
+	trafficZScore.DeleteLabelValues(zone.Tag)
+	if stddev == 0 {
+		return nil
+	}
+	z0 := (byHour[current] - mean) / stddev
+	trafficZScore.WithLabelValues(zone.Tag).Set(z0)
+	trafficAnomaly.WithLabelValues(zone.Tag, "up").Set(boolToFloat(z0 > anomalySigma))
+	trafficAnomaly.WithLabelValues(zone.Tag, "down").Set(boolToFloat(z0 < -anomalySigma))
+	return nil
+}
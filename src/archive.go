@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// The cycle archive writes every cycle's series as gzipped JSON lines to
+// an S3 compatible bucket: AWS S3, or GCS through its XML API with HMAC
+// keys (ARCHIVE_ENDPOINT=https://storage.googleapis.com).
+var (
+	// archiveURL is s3://bucket/prefix, empty disables the archive.
+	archiveURL       = ""
+	archiveEndpoint  = ""
+	archiveRegion    = "us-east-1"
+	archiveAccessKey = ""
+	archiveSecretKey = ""
+
+	archiveUploads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_exporter_archive_uploads_total",
+			Help: "Cycle snapshots written to the object storage archive by result (ok, error, skipped while the previous upload was running)",
+		},
+		[]string{"result"},
+	)
+
+	// archiveUploading is set while an upload runs; each holds a whole
+	// gzipped snapshot, so a slow bucket must not pile them up
+	archiveUploading atomic.Bool
+)
+
+func init() {
+	prometheus.MustRegister(archiveUploads)
+}
+
+type archiveTarget struct {
+	endpoint, bucket, prefix string
+}
+
+func parseArchiveURL() (archiveTarget, error) {
+	u, err := url.Parse(archiveURL)
+	if err != nil {
+		return archiveTarget{}, err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return archiveTarget{}, fmt.Errorf("ARCHIVE_URL must look like s3://bucket/prefix")
+	}
+	endpoint := archiveEndpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + archiveRegion + ".amazonaws.com"
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return archiveTarget{endpoint: strings.TrimRight(endpoint, "/"), bucket: u.Host, prefix: prefix}, nil
+}
+
+// checkArchive validates the settings at startup; the keys default to
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+func checkArchive() error {
+	if archiveURL == "" {
+		return nil
+	}
+	if archiveAccessKey == "" {
+		archiveAccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if archiveSecretKey == "" {
+		archiveSecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if archiveAccessKey == "" || archiveSecretKey == "" {
+		return fmt.Errorf("ARCHIVE_ACCESS_KEY and ARCHIVE_SECRET_KEY are required")
+	}
+	target, err := parseArchiveURL()
+	if err != nil {
+		return err
+	}
+	log.Println("[OK] Cycle archive:", target.endpoint+"/"+target.bucket+"/"+target.prefix)
+	return nil
+}
+
+type archiveSample struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// archiveCycle uploads the families of a finished cycle in the background,
+// keyed by the cycle end: <prefix>YYYY/MM/DD/<RFC 3339>.json.gz. The cycle
+// is skipped while the previous upload still runs.
+func archiveCycle(families []*dto.MetricFamily, at time.Time) {
+	if archiveURL == "" {
+		return
+	}
+	if !archiveUploading.CompareAndSwap(false, true) {
+		archiveUploads.WithLabelValues("skipped").Inc()
+		log.Println("[!] Предыдущая запись архива ещё идёт, цикл", at.UTC().Format(time.RFC3339), "не архивирован")
+		return
+	}
+	go func() {
+		defer archiveUploading.Store(false)
+		if err := uploadArchive(families, at); err != nil {
+			archiveUploads.WithLabelValues("error").Inc()
+			log.Println("[!] Ошибка записи архива цикла:", err)
+			return
+		}
+		archiveUploads.WithLabelValues("ok").Inc()
+	}()
+}
+
+func uploadArchive(families []*dto.MetricFamily, at time.Time) error {
+	target, err := parseArchiveURL()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			var value float64
+			switch {
+			case m.GetGauge() != nil:
+				value = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				value = m.GetCounter().GetValue()
+			default:
+				continue
+			}
+			labels := map[string]string{}
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			if err := enc.Encode(archiveSample{Metric: mf.GetName(), Labels: labels, Value: value}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	at = at.UTC()
+	key := target.prefix + at.Format("2006/01/02/") + at.Format(time.RFC3339) + ".json.gz"
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "PUT", target.endpoint+"/"+target.bucket+"/"+s3PathEscape(key), bytes.NewReader(buf.Bytes()))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	signS3(req, buf.Bytes(), time.Now())
+
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("PUT %s: status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// s3PathEscape encodes an object key as Signature Version 4 expects:
+// everything but unreserved characters and the slashes.
+func s3PathEscape(key string) string {
+	var b strings.Builder
+	for _, c := range []byte(key) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', strings.IndexByte("-_.~/", c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// signS3 adds the AWS Signature Version 4 headers to a request without a
+// query string.
+func signS3(req *http.Request, body []byte, now time.Time) {
+	payloadSum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payloadSum[:])
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-encoding;content-type;host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+		"content-encoding:" + req.Header.Get("Content-Encoding"),
+		"content-type:" + req.Header.Get("Content-Type"),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	canonicalSum := sha256.Sum256([]byte(canonical))
+	scope := day + "/" + archiveRegion + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalSum[:])
+
+	key := hmacSHA256([]byte("AWS4"+archiveSecretKey), day)
+	key = hmacSHA256(key, archiveRegion)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+archiveAccessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
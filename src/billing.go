@@ -0,0 +1,211 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	billingSubscriptionInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_billing_subscription_info",
+			Help: "Account subscriptions",
+		},
+		[]string{"account", "subscription_id", "rate_plan", "frequency", "state", "currency"},
+	)
+
+	billingSubscriptionPrice = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_billing_subscription_price",
+			Help: "Subscription price per billing period",
+		},
+		[]string{"account", "subscription_id"},
+	)
+
+	billingSubscriptionPeriodEnd = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_billing_subscription_period_end_timestamp_seconds",
+			Help: "End of the current subscription billing period",
+		},
+		[]string{"account", "subscription_id"},
+	)
+
+	// Usage based products such as Argo report their billed quantity as
+	// subscription components.
+	billingSubscriptionComponent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_billing_subscription_component_value",
+			Help: "Billed quantity of a subscription component",
+		},
+		[]string{"account", "subscription_id", "component"},
+	)
+
+	billingWorkersRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_billing_workers_requests",
+			Help: "Workers requests since the start of the month",
+		},
+		[]string{"account"},
+	)
+
+	billingWorkersBillableRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_billing_workers_billable_requests",
+			Help: "Workers requests since the start of the month beyond the included amount",
+		},
+		[]string{"account"},
+	)
+
+	billingStreamMinutes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_billing_stream_minutes_viewed",
+			Help: "Stream minutes viewed since the start of the month",
+		},
+		[]string{"account"},
+	)
+
+	billingR2StorageBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_billing_r2_storage_bytes",
+			Help: "Stored R2 bytes (payload and metadata) per bucket",
+		},
+		[]string{"account", "bucket"},
+	)
+
+	workersIncludedRequests = 10000000
+)
+
+func init() {
+	cycleRegistry.MustRegister(billingSubscriptionInfo)
+	cycleRegistry.MustRegister(billingSubscriptionPrice)
+	cycleRegistry.MustRegister(billingSubscriptionPeriodEnd)
+	cycleRegistry.MustRegister(billingSubscriptionComponent)
+	cycleRegistry.MustRegister(billingWorkersRequests)
+	cycleRegistry.MustRegister(billingWorkersBillableRequests)
+	cycleRegistry.MustRegister(billingStreamMinutes)
+	cycleRegistry.MustRegister(billingR2StorageBytes)
+
+	registerCollector(collector{
+		name:     "billing",
+		scope:    "Billing",
+		datasets: []string{"workersInvocationsAdaptive", "streamMinutesViewedAdaptiveGroups", "r2StorageAdaptiveGroups"},
+		account:  fetchBilling,
+	})
+}
+
+const billingUsageQuery = `query ($accountTag: string!, $monthStart: Time!, $monthStartDate: Date!, $storageSince: Time!) {
+  viewer {
+    accounts(filter: { accountTag: $accountTag }) {
+      workersInvocationsAdaptive(limit: 1, filter: { datetime_geq: $monthStart }) {
+        sum { requests }
+      }
+      streamMinutesViewedAdaptiveGroups(limit: 1, filter: { date_geq: $monthStartDate }) {
+        sum { minutesViewed }
+      }
+      r2StorageAdaptiveGroups(limit: 1000, filter: { datetime_geq: $storageSince }, orderBy: [datetime_DESC]) {
+        max { payloadSize metadataSize }
+        dimensions { bucketName datetime }
+      }
+    }
+  }
+}`
+
+func fetchBilling(account Account) error {
+	var subscriptions []struct {
+		ID               string    `json:"id"`
+		State            string    `json:"state"`
+		Price            float64   `json:"price"`
+		Currency         string    `json:"currency"`
+		Frequency        string    `json:"frequency"`
+		CurrentPeriodEnd time.Time `json:"current_period_end"`
+		RatePlan         struct {
+			ID         string `json:"id"`
+			PublicName string `json:"public_name"`
+		} `json:"rate_plan"`
+		ComponentValues []struct {
+			Name  string  `json:"name"`
+			Value float64 `json:"value"`
+		} `json:"component_values"`
+	}
+	if _, err := cfGet("/accounts/"+account.ID+"/subscriptions", &subscriptions); err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	billingSubscriptionInfo.DeletePartialMatch(byAccount)
+	billingSubscriptionPrice.DeletePartialMatch(byAccount)
+	billingSubscriptionPeriodEnd.DeletePartialMatch(byAccount)
+	billingSubscriptionComponent.DeletePartialMatch(byAccount)
+
+	for _, s := range subscriptions {
+		plan := s.RatePlan.PublicName
+		if plan == "" {
+			plan = s.RatePlan.ID
+		}
+		billingSubscriptionInfo.WithLabelValues(account.Name, s.ID, plan, s.Frequency, s.State, s.Currency).Set(1)
+		billingSubscriptionPrice.WithLabelValues(account.Name, s.ID).Set(s.Price)
+		if !s.CurrentPeriodEnd.IsZero() {
+			billingSubscriptionPeriodEnd.WithLabelValues(account.Name, s.ID).Set(float64(s.CurrentPeriodEnd.Unix()))
+		}
+		for _, c := range s.ComponentValues {
+			billingSubscriptionComponent.WithLabelValues(account.Name, s.ID, c.Name).Set(c.Value)
+		}
+	}
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	a, err := queryAccount[struct {
+		WorkersInvocationsAdaptive []struct {
+			Sum struct {
+				Requests float64 `json:"requests"`
+			} `json:"sum"`
+		} `json:"workersInvocationsAdaptive"`
+		StreamMinutesViewedAdaptiveGroups []struct {
+			Sum struct {
+				MinutesViewed float64 `json:"minutesViewed"`
+			} `json:"sum"`
+		} `json:"streamMinutesViewedAdaptiveGroups"`
+		R2StorageAdaptiveGroups []struct {
+			Max struct {
+				PayloadSize  float64 `json:"payloadSize"`
+				MetadataSize float64 `json:"metadataSize"`
+			} `json:"max"`
+			Dimensions struct {
+				BucketName string `json:"bucketName"`
+			} `json:"dimensions"`
+		} `json:"r2StorageAdaptiveGroups"`
+	}](account, billingUsageQuery, map[string]any{
+		"monthStart":     monthStart.Format(time.RFC3339),
+		"monthStartDate": monthStart.Format("2006-01-02"),
+		"storageSince":   now.Add(-24 * time.Hour).Format(time.RFC3339),
+	})
+	if err != nil || a == nil {
+		return err
+	}
+
+	workersRequests := 0.0
+	for _, g := range a.WorkersInvocationsAdaptive {
+		workersRequests += g.Sum.Requests
+	}
+	billingWorkersRequests.WithLabelValues(account.Name).Set(workersRequests)
+	billingWorkersBillableRequests.WithLabelValues(account.Name).Set(max(0, workersRequests-float64(workersIncludedRequests)))
+
+	streamMinutes := 0.0
+	for _, g := range a.StreamMinutesViewedAdaptiveGroups {
+		streamMinutes += g.Sum.MinutesViewed
+	}
+	billingStreamMinutes.WithLabelValues(account.Name).Set(streamMinutes)
+
+	// groups are ordered newest first, keep the latest sample per bucket
+	billingR2StorageBytes.DeletePartialMatch(byAccount)
+	seen := map[string]bool{}
+	for _, g := range a.R2StorageAdaptiveGroups {
+		if seen[g.Dimensions.BucketName] {
+			continue
+		}
+		seen[g.Dimensions.BucketName] = true
+		billingR2StorageBytes.WithLabelValues(account.Name, g.Dimensions.BucketName).Set(g.Max.PayloadSize + g.Max.MetadataSize)
+	}
+	return nil
+}
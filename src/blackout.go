@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// blackoutWindow is a period without collection. Either Start and End
+// give a single period, or Days, From and To (HH:MM, UTC) a weekly one;
+// a weekly window with To before From ends on the next day.
+type blackoutWindow struct {
+	Name  string    `yaml:"name"`
+	Start time.Time `yaml:"start"`
+	End   time.Time `yaml:"end"`
+	// Days are weekday abbreviations (mon..sun), empty for every day.
+	Days []string `yaml:"days"`
+	From string   `yaml:"from"`
+	To   string   `yaml:"to"`
+}
+
+var blackoutMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cloudflare_exporter_blackout_active",
+		Help: "1 while the blackout window pauses collection, the last cycle's metrics are served meanwhile",
+	},
+	[]string{"window"},
+)
+
+func init() {
+	prometheus.MustRegister(blackoutMetric)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// minuteOfDay parses HH:MM.
+func minuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// checkBlackouts validates the configured windows at startup.
+func checkBlackouts() error {
+	for i, w := range config.Blackouts {
+		if w.Name == "" {
+			return fmt.Errorf("blackouts[%d]: name is required", i)
+		}
+		if !w.Start.IsZero() || !w.End.IsZero() {
+			if !w.End.After(w.Start) {
+				return fmt.Errorf("blackout %s: end must be after start", w.Name)
+			}
+			blackoutMetric.WithLabelValues(w.Name).Set(0)
+			continue
+		}
+		if _, err := minuteOfDay(w.From); err != nil {
+			return fmt.Errorf("blackout %s: from: %v", w.Name, err)
+		}
+		if _, err := minuteOfDay(w.To); err != nil {
+			return fmt.Errorf("blackout %s: to: %v", w.Name, err)
+		}
+		for _, day := range w.Days {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("blackout %s: unknown day %q", w.Name, day)
+			}
+		}
+		blackoutMetric.WithLabelValues(w.Name).Set(0)
+	}
+	return nil
+}
+
+func (w blackoutWindow) active(now time.Time) bool {
+	if !w.Start.IsZero() || !w.End.IsZero() {
+		return !now.Before(w.Start) && now.Before(w.End)
+	}
+	now = now.UTC()
+	from, _ := minuteOfDay(w.From)
+	to, _ := minuteOfDay(w.To)
+	minute := now.Hour()*60 + now.Minute()
+	// past midnight the window belongs to the day it started on
+	day := now.Weekday()
+	switch {
+	case from <= to:
+		if minute < from || minute >= to {
+			return false
+		}
+	case minute >= from:
+	case minute < to:
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// lastBlackout is the window active at the previous check. The main
+	// loop, the burst loop and scrape mode all check.
+	lastBlackout      = ""
+	lastBlackoutMutex = &sync.Mutex{}
+)
+
+// inBlackout updates the blackout gauges and returns the name of the
+// active window, or "" when collection may run.
+func inBlackout() string {
+	now := time.Now()
+	name := ""
+	for _, w := range config.Blackouts {
+		active := w.active(now)
+		blackoutMetric.WithLabelValues(w.Name).Set(boolToFloat(active))
+		if active && name == "" {
+			name = w.Name
+		}
+	}
+	lastBlackoutMutex.Lock()
+	defer lastBlackoutMutex.Unlock()
+	if name != lastBlackout {
+		if name != "" {
+			log.Println("[OK] Blackout window", name, "started, collection paused")
+		} else {
+			log.Println("[OK] Blackout window", lastBlackout, "ended, collection resumed")
+		}
+		lastBlackout = name
+	}
+	return name
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlackoutWindowActive(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	tests := []struct {
+		name   string
+		window blackoutWindow
+		now    string
+		want   bool
+	}{
+		// 2026-10-16 is a Friday
+		{"absolute inside", blackoutWindow{Start: at("2026-10-16T10:00:00Z"), End: at("2026-10-16T12:00:00Z")}, "2026-10-16T11:00:00Z", true},
+		{"absolute at start", blackoutWindow{Start: at("2026-10-16T10:00:00Z"), End: at("2026-10-16T12:00:00Z")}, "2026-10-16T10:00:00Z", true},
+		{"absolute at end", blackoutWindow{Start: at("2026-10-16T10:00:00Z"), End: at("2026-10-16T12:00:00Z")}, "2026-10-16T12:00:00Z", false},
+		{"daily inside", blackoutWindow{From: "02:00", To: "04:00"}, "2026-10-16T03:00:00Z", true},
+		{"daily at end", blackoutWindow{From: "02:00", To: "04:00"}, "2026-10-16T04:00:00Z", false},
+		{"daily other timezone", blackoutWindow{From: "02:00", To: "04:00"}, "2026-10-16T05:30:00+02:00", true},
+		{"weekday matches", blackoutWindow{Days: []string{"mon", "fri"}, From: "02:00", To: "04:00"}, "2026-10-16T03:00:00Z", true},
+		{"weekday does not match", blackoutWindow{Days: []string{"mon", "tue"}, From: "02:00", To: "04:00"}, "2026-10-16T03:00:00Z", false},
+		{"weekday upper case", blackoutWindow{Days: []string{"FRI"}, From: "02:00", To: "04:00"}, "2026-10-16T03:00:00Z", true},
+		{"past midnight before midnight", blackoutWindow{Days: []string{"fri"}, From: "22:00", To: "02:00"}, "2026-10-16T23:00:00Z", true},
+		{"past midnight after midnight", blackoutWindow{Days: []string{"fri"}, From: "22:00", To: "02:00"}, "2026-10-17T01:00:00Z", true},
+		{"past midnight next night", blackoutWindow{Days: []string{"fri"}, From: "22:00", To: "02:00"}, "2026-10-18T01:00:00Z", false},
+		{"past midnight previous day", blackoutWindow{Days: []string{"fri"}, From: "22:00", To: "02:00"}, "2026-10-16T01:00:00Z", false},
+		{"past midnight gap", blackoutWindow{From: "22:00", To: "02:00"}, "2026-10-16T12:00:00Z", false},
+		{"past midnight week wrap", blackoutWindow{Days: []string{"sat"}, From: "22:00", To: "02:00"}, "2026-10-18T01:00:00Z", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.active(at(tt.now)); got != tt.want {
+				t.Errorf("active(%s) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
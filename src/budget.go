@@ -0,0 +1,198 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Cloudflare limits API calls per user over a sliding five minute window.
+// The budgets count our own calls so that a cycle slows down instead of
+// running into 429s for the tail of the zone list.
+const budgetWindow = 5 * time.Minute
+
+var (
+	restBudgetLimit    = 1200
+	graphqlBudgetLimit = 300
+
+	restBudget    = &requestBudget{api: "rest", limit: &restBudgetLimit}
+	graphqlBudget = &requestBudget{api: "graphql", limit: &graphqlBudgetLimit}
+
+	budgetRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_exporter_api_budget_remaining",
+			Help: "Calls left in the current five minute window per API",
+		},
+		[]string{"api"},
+	)
+
+	rateLimitedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_exporter_api_rate_limited_total",
+			Help: "Cloudflare API responses with status 429",
+		},
+		[]string{"api"},
+	)
+
+	collectorShed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_exporter_collector_shed",
+			Help: "1 if a low-priority collector was skipped in the last cycle because of rate pressure",
+		},
+		[]string{"collector"},
+	)
+
+	budgetWaitSeconds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_exporter_api_budget_wait_seconds_total",
+			Help: "Time fetches were delayed because the API budget ran low",
+		},
+		[]string{"api"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(budgetRemaining)
+	prometheus.MustRegister(budgetWaitSeconds)
+	prometheus.MustRegister(rateLimitedTotal)
+	prometheus.MustRegister(collectorShed)
+}
+
+type requestBudget struct {
+	api   string
+	limit *int
+	mu    sync.Mutex
+	calls []time.Time
+	// limited are the 429 responses within the window
+	limited []time.Time
+}
+
+// expire drops calls older than the window. The caller must hold b.mu.
+func (b *requestBudget) expire(now time.Time) {
+	i := 0
+	for i < len(b.calls) && now.Sub(b.calls[i]) >= budgetWindow {
+		i++
+	}
+	b.calls = b.calls[i:]
+	i = 0
+	for i < len(b.limited) && now.Sub(b.limited[i]) >= budgetWindow {
+		i++
+	}
+	b.limited = b.limited[i:]
+}
+
+func (b *requestBudget) remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire(time.Now())
+	return max(0, *b.limit-len(b.calls))
+}
+
+// take records one call.
+func (b *requestBudget) take() {
+	b.mu.Lock()
+	now := time.Now()
+	b.expire(now)
+	b.calls = append(b.calls, now)
+	left := max(0, *b.limit-len(b.calls))
+	b.mu.Unlock()
+	budgetRemaining.WithLabelValues(b.api).Set(float64(left))
+}
+
+// headroom is kept free for the several calls a single fetch may make.
+func (b *requestBudget) headroom() int {
+	return max(1, *b.limit/20)
+}
+
+// wait blocks until the budget has headroom again.
+func (b *requestBudget) wait() {
+	start := time.Now()
+	logged := false
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.expire(now)
+		if *b.limit <= 0 || *b.limit-len(b.calls) >= b.headroom() {
+			b.mu.Unlock()
+			break
+		}
+		// sleep until enough of the oldest calls leave the window
+		n := len(b.calls) - (*b.limit - b.headroom())
+		delay := budgetWindow - now.Sub(b.calls[min(n, len(b.calls))-1])
+		b.mu.Unlock()
+		if !logged {
+			log.Printf("[!] Бюджет %s API почти исчерпан, ждём %s", b.api, delay.Round(time.Second))
+			logged = true
+		}
+		time.Sleep(max(delay, time.Second))
+	}
+	if waited := time.Since(start); waited > time.Millisecond {
+		budgetWaitSeconds.WithLabelValues(b.api).Add(waited.Seconds())
+	}
+}
+
+// waitForBudget delays the next fetch while either API budget is low.
+func waitForBudget() {
+	restBudget.wait()
+	graphqlBudget.wait()
+}
+
+// zonePriority returns the priority of the last matching zones entry that
+// sets one; zones with higher priority are fetched first.
+func zonePriority(zone Zone) int {
+	priority := 0
+	for _, zc := range zoneConfigs(zone) {
+		if zc.Priority != 0 {
+			priority = zc.Priority
+		}
+	}
+	return priority
+}
+
+// rateLimited records a 429 response.
+func (b *requestBudget) rateLimited() {
+	b.mu.Lock()
+	now := time.Now()
+	b.expire(now)
+	b.limited = append(b.limited, now)
+	b.mu.Unlock()
+	rateLimitedTotal.WithLabelValues(b.api).Inc()
+}
+
+// underPressure reports sustained 429s or an exhausted budget.
+func (b *requestBudget) underPressure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire(time.Now())
+	exhausted := *b.limit > 0 && *b.limit-len(b.calls) < b.headroom()
+	return exhausted || (shedRateLimited > 0 && len(b.limited) >= shedRateLimited)
+}
+
+var (
+	// lowPriorityCollectors are skipped while the API is under pressure.
+	lowPriorityCollectors = ""
+	// shedRateLimited is the number of 429s within five minutes that
+	// counts as sustained rate limiting.
+	shedRateLimited = 3
+)
+
+// shedCollector reports whether a low-priority collector should be skipped
+// this cycle and exports the decision.
+func shedCollector(c collector) bool {
+	low := false
+	for _, name := range strings.Split(lowPriorityCollectors, ",") {
+		low = low || strings.TrimSpace(name) == c.name
+	}
+	if !low {
+		return false
+	}
+	shed := restBudget.underPressure() || graphqlBudget.underPressure()
+	collectorShed.WithLabelValues(c.name).Set(boolToFloat(shed))
+	if shed {
+		log.Println("[!] Нагрузка на API, пропускаем коллектор", c.name)
+	}
+	return shed
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestBudget(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name          string
+		limit         int
+		calls         []time.Duration
+		limited       int
+		remaining     int
+		underPressure bool
+	}{
+		{"empty", 100, nil, 0, 100, false},
+		{"expired calls are dropped", 100, []time.Duration{-10 * time.Minute, -budgetWindow, -time.Minute}, 0, 99, false},
+		{"at headroom", 100, make([]time.Duration, 95), 0, 5, false},
+		{"below headroom", 100, make([]time.Duration, 96), 0, 4, true},
+		{"small limit keeps one call headroom", 10, make([]time.Duration, 9), 0, 1, false},
+		{"over limit", 10, make([]time.Duration, 12), 0, 0, true},
+		{"unlimited", 0, make([]time.Duration, 12), 0, 0, false},
+		{"sustained 429s", 100, nil, 3, 100, true},
+		{"few 429s", 100, nil, 2, 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &requestBudget{api: "test", limit: &tt.limit}
+			for _, d := range tt.calls {
+				b.calls = append(b.calls, now.Add(d))
+			}
+			for range tt.limited {
+				b.limited = append(b.limited, now)
+			}
+			if got := b.remaining(); got != tt.remaining {
+				t.Errorf("remaining() = %d, want %d", got, tt.remaining)
+			}
+			if got := b.underPressure(); got != tt.underPressure {
+				t.Errorf("underPressure() = %v, want %v", got, tt.underPressure)
+			}
+		})
+	}
+}
+
+func TestRequestBudgetTake(t *testing.T) {
+	limit := 3
+	b := &requestBudget{api: "test", limit: &limit}
+	for want := 2; want >= 0; want-- {
+		b.take()
+		if got := b.remaining(); got != want {
+			t.Fatalf("remaining() = %d, want %d", got, want)
+		}
+	}
+	b.take()
+	if got := b.remaining(); got != 0 {
+		t.Errorf("remaining() = %d after exceeding the limit, want 0", got)
+	}
+}
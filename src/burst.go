@@ -0,0 +1,198 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A zone whose recent 5xx or threat ratio crosses its threshold enters
+// burst mode: every burstInterval the burst collector and burstCollectors
+// re-poll it over the last burstWindow, until the ratios stay below the
+// thresholds for burstDuration.
+var (
+	// burstErrorRatio and burstThreatRatio are the triggers, 0 disables.
+	burstErrorRatio  = 0.0
+	burstThreatRatio = 0.0
+	// burstMinRequests keeps quiet zones from bursting on a few errors.
+	burstMinRequests = 100.0
+	burstWindow      = 5 * time.Minute
+	burstInterval    = time.Minute
+	burstDuration    = 15 * time.Minute
+	burstCollectors  = "health"
+
+	burstUntil      = map[string]time.Time{}
+	burstUntilMutex = &sync.Mutex{}
+
+	burstActiveMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_burst_active",
+			Help: "1 while the zone is polled every BURST_INTERVAL after crossing BURST_ERROR_RATIO or BURST_THREAT_RATIO",
+		},
+		[]string{"zone_tag"},
+	)
+
+	recentRequestsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_recent_requests",
+			Help: "Requests per zone over the last BURST_WINDOW",
+		},
+		[]string{"zone_tag"},
+	)
+
+	recentRatioMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_recent_ratio",
+			Help: "Share of requests per zone over the last BURST_WINDOW answered with 5xx (errors) or mitigated by the firewall (threats)",
+		},
+		[]string{"zone_tag", "kind"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(burstActiveMetric)
+	cycleRegistry.MustRegister(recentRequestsMetric)
+	cycleRegistry.MustRegister(recentRatioMetric)
+
+	registerCollector(collector{
+		name:     "burst",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups", "firewallEventsAdaptiveGroups"},
+		zone:     fetchBurst,
+	})
+}
+
+// fetchBurst reuses the health query over the burst window and moves the
+// zone in or out of burst mode.
+func fetchBurst(zone Zone) error {
+	now := time.Now().UTC()
+	window := map[string]any{
+		"datetime_geq": now.Add(-burstWindow).Format(time.RFC3339),
+		"datetime_lt":  now.Format(time.RFC3339),
+	}
+	errorsFilter := map[string]any{"edgeResponseStatus_geq": 500, "edgeResponseStatus_lt": 600}
+	threatsFilter := map[string]any{"action_in": []string{"block", "challenge", "jschallenge", "managed_challenge"}}
+	for k, v := range window {
+		errorsFilter[k] = v
+		threatsFilter[k] = v
+	}
+
+	type countGroup struct {
+		Count float64 `json:"count"`
+	}
+	z, err := queryZone[struct {
+		All     []countGroup `json:"all"`
+		Errors  []countGroup `json:"errors"`
+		Threats []countGroup `json:"threats"`
+	}](zone, healthQuery, map[string]any{
+		"all":     zoneFilter(zone, "httpRequestsAdaptiveGroups", window),
+		"errors":  zoneFilter(zone, "httpRequestsAdaptiveGroups", errorsFilter),
+		"threats": zoneFilter(zone, "firewallEventsAdaptiveGroups", threatsFilter),
+	})
+	if err != nil {
+		return err
+	}
+
+	first := func(groups []countGroup) float64 {
+		if len(groups) == 0 {
+			return 0
+		}
+		return groups[0].Count
+	}
+	total, errorRatio, threatRatio := 0.0, 0.0, 0.0
+	if z != nil {
+		total = first(z.All)
+	}
+	if total > 0 {
+		errorRatio = first(z.Errors) / total
+		threatRatio = first(z.Threats) / total
+	}
+	recentRequestsMetric.WithLabelValues(zone.Tag).Set(total)
+	recentRatioMetric.WithLabelValues(zone.Tag, "errors").Set(errorRatio)
+	recentRatioMetric.WithLabelValues(zone.Tag, "threats").Set(threatRatio)
+
+	triggered := total >= burstMinRequests &&
+		(burstErrorRatio > 0 && errorRatio >= burstErrorRatio || burstThreatRatio > 0 && threatRatio >= burstThreatRatio)
+
+	burstUntilMutex.Lock()
+	until, bursting := burstUntil[zone.Tag]
+	switch {
+	case triggered:
+		burstUntil[zone.Tag] = now.Add(burstDuration)
+		if !bursting {
+			log.Printf("[!] Всплеск на зоне %s (5xx %.1f%%, угрозы %.1f%%), опрашиваем каждые %s", zone.Tag, 100*errorRatio, 100*threatRatio, burstInterval)
+		}
+	case bursting && now.After(until):
+		delete(burstUntil, zone.Tag)
+		log.Println("[OK] Zone", zone.Tag, "back to normal polling")
+	}
+	_, bursting = burstUntil[zone.Tag]
+	burstUntilMutex.Unlock()
+	burstActiveMetric.WithLabelValues(zone.Tag).Set(boolToFloat(bursting))
+	return nil
+}
+
+// burstZones returns the zones currently in burst mode.
+func burstZones() []Zone {
+	burstUntilMutex.Lock()
+	tags := map[string]bool{}
+	for tag := range burstUntil {
+		tags[tag] = true
+	}
+	burstUntilMutex.Unlock()
+	if len(tags) == 0 {
+		return nil
+	}
+	zonesMutex.RLock()
+	defer zonesMutex.RUnlock()
+	selected := []Zone{}
+	for _, zone := range zones {
+		if tags[zone.Tag] {
+			selected = append(selected, zone)
+		}
+	}
+	return selected
+}
+
+// burstLoop re-polls the bursting zones between cycles on the leader.
+func burstLoop() {
+	for range time.Tick(burstInterval) {
+		if !isLeader() || inBlackout() != "" || !enabledCollectors["burst"] {
+			continue
+		}
+		selected := burstZones()
+		if len(selected) == 0 {
+			continue
+		}
+		names := map[string]bool{"burst": true}
+		for _, name := range strings.Split(burstCollectors, ",") {
+			names[strings.TrimSpace(name)] = true
+		}
+
+		collectMutex.Lock()
+		for _, c := range collectors {
+			if !names[c.name] || !enabledCollectors[c.name] || c.zone == nil {
+				continue
+			}
+			runPool(len(selected), func(i int) {
+				waitForBudget()
+				collectZone(c, selected[i])
+			})
+		}
+		if !pullMode() {
+			swapCycle()
+			publishMetrics()
+		}
+		collectMutex.Unlock()
+	}
+}
+
+// forgetBurst is called when the zone's series were removed.
+func forgetBurst(zoneTag string) {
+	burstUntilMutex.Lock()
+	defer burstUntilMutex.Unlock()
+	delete(burstUntil, zoneTag)
+}
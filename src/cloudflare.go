@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type cfAPIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type cfResultInfo struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	TotalPages int `json:"total_pages"`
+	Count      int `json:"count"`
+	TotalCount int `json:"total_count"`
+}
+
+// cfResponse is the common envelope of the Cloudflare REST API v4.
+type cfResponse struct {
+	Success    bool            `json:"success"`
+	Errors     []cfAPIError    `json:"errors"`
+	Result     json.RawMessage `json:"result"`
+	ResultInfo cfResultInfo    `json:"result_info"`
+}
+
+// cfRequestError is returned by cfGet when Cloudflare answered with
+// success=false.
+type cfRequestError struct {
+	Path   string
+	Status int
+	Errors []cfAPIError
+}
+
+func (e *cfRequestError) Error() string {
+	return fmt.Sprintf("GET %s: status %d: %s", e.Path, e.Status, cfErrorsString(e.Errors))
+}
+
+func isNotFound(err error) bool {
+	var reqErr *cfRequestError
+	return errors.As(err, &reqErr) && reqErr.Status == http.StatusNotFound
+}
+
+func cfErrorsString(errs []cfAPIError) string {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, fmt.Sprintf("%d: %s", e.Code, e.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// cfGet calls the REST API at path (relative to cfBase) and decodes the
+// envelope result into result.
+func cfGet(path string, result any) (cfResultInfo, error) {
+	return cfGetToken(tokenFor(path, nil), path, result)
+}
+
+// cfGetToken is cfGet with an explicit API token.
+func cfGetToken(token, path string, result any) (cfResultInfo, error) {
+	resp, err := restBudget.do(httpRequestTimeout, func(ctx context.Context) *http.Request {
+		req, _ := http.NewRequestWithContext(ctx, "GET", cfBase+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	})
+	if err != nil {
+		return cfResultInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	var data cfResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return cfResultInfo{}, fmt.Errorf("GET %s: %w: %w", path, statusError{resp.StatusCode}, err)
+	}
+	if !data.Success {
+		return cfResultInfo{}, &cfRequestError{Path: path, Status: resp.StatusCode, Errors: data.Errors}
+	}
+	if result != nil && len(data.Result) > 0 {
+		if err := json.Unmarshal(data.Result, result); err != nil {
+			return cfResultInfo{}, fmt.Errorf("GET %s: %w", path, err)
+		}
+	}
+	return data.ResultInfo, nil
+}
+
+var (
+	graphqlTimeout          = 60 * time.Second
+	graphqlMaxResponseBytes = 32 << 20
+
+	graphqlOversized = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cloudflare_exporter_graphql_oversized_responses_total",
+		Help: "GraphQL responses aborted for exceeding GRAPHQL_MAX_RESPONSE_BYTES",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(graphqlOversized)
+}
+
+type gqlError struct {
+	Message    string `json:"message"`
+	Path       []any  `json:"path"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+}
+
+// graphqlErrors is returned by cfGraphQL when the response has errors.
+type graphqlErrors []gqlError
+
+func (e graphqlErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, ge := range e {
+		msgs = append(msgs, ge.Message)
+	}
+	return "graphql: " + strings.Join(msgs, "; ")
+}
+
+// cfGraphQL runs query against the GraphQL Analytics API and decodes the
+// "data" object into data. Fields Cloudflare no longer knows are dropped
+// from the query, see disableUnknownField.
+func cfGraphQL(query string, variables map[string]any, data any) error {
+	query = withoutDisabledFields(query)
+	for {
+		err := cfGraphQLOnce(query, variables, data)
+		stripped, ok := disableUnknownField(query, err)
+		if !ok {
+			return err
+		}
+		query = stripped
+	}
+}
+
+func cfGraphQLOnce(query string, variables map[string]any, data any) error {
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+
+	cacheKey := responseCacheKey(query, variables)
+	fromCache := func(cause error) error {
+		if cached, ok := responseCacheGet(cacheKey); ok {
+			log.Printf("[!] Cloudflare недоступен (%v), используем кеш", cause)
+			return json.Unmarshal(cached, data)
+		}
+		return cause
+	}
+
+	token := tokenFor("", variables)
+	resp, err := graphqlBudget.do(graphqlTimeout, func(ctx context.Context) *http.Request {
+		req, _ := http.NewRequestWithContext(ctx, "POST", cfBase+"/graphql", bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	})
+	if err != nil {
+		return fromCache(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("graphql: %w", statusError{resp.StatusCode})
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fromCache(fmt.Errorf("graphql: %w", statusError{resp.StatusCode}))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(graphqlMaxResponseBytes)+1))
+	if err != nil {
+		return fromCache(err)
+	}
+	if len(body) > graphqlMaxResponseBytes {
+		graphqlOversized.Inc()
+		return fmt.Errorf("graphql: response larger than %d bytes", graphqlMaxResponseBytes)
+	}
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []gqlError      `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("graphql: %w: %w", statusError{resp.StatusCode}, err)
+	}
+	if len(result.Errors) > 0 {
+		return graphqlErrors(result.Errors)
+	}
+	if len(result.Data) == 0 || string(result.Data) == "null" {
+		return fmt.Errorf("graphql: status %d: empty data", resp.StatusCode)
+	}
+	if err := json.Unmarshal(result.Data, data); err != nil {
+		return err
+	}
+	responseCachePut(cacheKey, result.Data)
+	return nil
+}
+
+// cfGetAll fetches every page of a paginated list endpoint. path must not
+// contain page or per_page parameters.
+func cfGetAll[T any](path string, perPage int) ([]T, error) {
+	return cfGetAllToken[T](tokenFor(path, nil), path, perPage)
+}
+
+// cfGetAllToken is cfGetAll with an explicit API token.
+func cfGetAllToken[T any](token, path string, perPage int) ([]T, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	all := []T{}
+	for page := 1; ; page++ {
+		var items []T
+		info, err := cfGetToken(token, fmt.Sprintf("%s%spage=%d&per_page=%d", path, sep, page, perPage), &items)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) == 0 || page >= info.TotalPages {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statusError is an HTTP error status of a Cloudflare response that had no
+// usable body.
+type statusError struct {
+	Status int
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("status %d", e.Status)
+}
+
+var collectErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cf_collect_errors_total",
+		Help: "Failed collector fetches per zone (account:<name> for accounts) by reason: auth, rate_limit, timeout, schema, entitlement, parse, unavailable, other",
+	},
+	[]string{"zone_tag", "collector", "reason"},
+)
+
+func init() {
+	prometheus.MustRegister(collectErrors)
+}
+
+// errorReason sorts a collection failure into a stable reason, so alerts
+// can tell a broken token from a Cloudflare outage.
+func errorReason(err error) string {
+	var status statusError
+	var reqErr *cfRequestError
+	var gqlErrs graphqlErrors
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	httpStatus := 0
+	if errors.As(err, &status) {
+		httpStatus = status.Status
+	} else if errors.As(err, &reqErr) {
+		httpStatus = reqErr.Status
+	}
+	msg := strings.ToLower(err.Error())
+
+	switch {
+	case errors.Is(err, errNotEntitled):
+		return "entitlement"
+	case httpStatus == http.StatusTooManyRequests || strings.Contains(msg, "rate limit"):
+		return "rate_limit"
+	case isPermissionError(err):
+		return "auth"
+	case errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &gqlErrs) && (unknownFieldPattern.MatchString(msg) || strings.Contains(msg, "unknown argument") || strings.Contains(msg, "unknown type")):
+		return "schema"
+	case errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || strings.Contains(msg, "empty data"):
+		return "parse"
+	case httpStatus >= http.StatusInternalServerError || errors.As(err, &netErr):
+		return "unavailable"
+	}
+	return "other"
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// collector is a data source polled every cycle. Zone collectors are called
+// once per discovered zone, account collectors once per account that owns at
+// least one discovered zone.
+type collector struct {
+	name string
+	// scope is the token permission group the collector depends on
+	scope string
+	// datasets are the GraphQL datasets the collector queries
+	datasets []string
+	zone     func(zone Zone) error
+	account  func(account Account) error
+}
+
+var (
+	collectorsList    = "traffic"
+	collectors        = []collector{}
+	enabledCollectors = map[string]bool{}
+
+	// fetchConcurrency is the number of zones or accounts a collector
+	// fetches at once. Every fetch still waits for the API budget, so
+	// raising it does not push the exporter past Cloudflare's limits.
+	fetchConcurrency = 4
+)
+
+func registerCollector(c collector) {
+	collectors = append(collectors, c)
+}
+
+// setEnabledCollectors parses the comma-separated COLLECTORS value.
+func setEnabledCollectors(list string) {
+	known := map[string]bool{}
+	for _, c := range collectors {
+		known[c.name] = true
+	}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			log.Println("[!] Неизвестный коллектор:", name)
+			continue
+		}
+		enabledCollectors[name] = true
+	}
+}
+
+func collectAll() {
+	// a cycle takes minutes; holding zonesMutex that long would block the
+	// admin API and zone refreshes, and every reader queued behind them
+	zonesMutex.RLock()
+	zones := slices.Clone(zones)
+	accounts := slices.Clone(accounts)
+	zonesMutex.RUnlock()
+
+	zonePausedMetric.Reset()
+	active := []Zone{}
+	for _, zone := range zones {
+		paused := zonePaused(zone)
+		zonePausedMetric.WithLabelValues(zone.Tag).Set(boolToFloat(paused))
+		if !paused {
+			active = append(active, zone)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool { return zonePriority(active[i]) > zonePriority(active[j]) })
+	checkZoneDatasets(active)
+
+	pending := 0
+	zonesOf := map[string][]Zone{}
+	for _, c := range collectors {
+		if !enabledCollectors[c.name] {
+			continue
+		}
+		if c.account != nil {
+			pending += len(accounts)
+		}
+		if c.zone != nil {
+			zonesOf[c.name] = zonesWithDatasets(c, active)
+			pending += len(zonesOf[c.name])
+		}
+	}
+	fetchesPending.Store(int64(pending))
+	defer fetchesPending.Store(0)
+
+	for _, c := range collectors {
+		if !enabledCollectors[c.name] {
+			continue
+		}
+		if shedCollector(c) {
+			if c.account != nil {
+				fetchesPending.Add(-int64(len(accounts)))
+			}
+			if c.zone != nil {
+				fetchesPending.Add(-int64(len(zonesOf[c.name])))
+			}
+			continue
+		}
+		if c.account != nil {
+			runPool(len(accounts), func(i int) {
+				account := accounts[i]
+				waitForBudget()
+				start := time.Now()
+				err := c.account(account)
+				recordFetch(c.name, "account:"+account.Name, start, err)
+				fetchesPending.Add(-1)
+				if err != nil {
+					log.Printf("[!] Ошибка коллектора %s для аккаунта %s: %v", c.name, account.Name, err)
+				}
+			})
+		}
+		if c.zone != nil {
+			selected := zonesOf[c.name]
+			runPool(len(selected), func(i int) {
+				waitForBudget()
+				collectZone(c, selected[i])
+				fetchesPending.Add(-1)
+			})
+		}
+	}
+}
+
+// runPool calls fn for 0..n-1 on at most fetchConcurrency goroutines and
+// waits for all of them. Items are handed out in order, so higher priority
+// zones still start first.
+func runPool(n int, fn func(i int)) {
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(fetchConcurrency, 1), n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+// collectZone runs a zone collector once and records the outcome.
+func collectZone(c collector, zone Zone) error {
+	start := time.Now()
+	err := c.zone(zone)
+	recordFetch(c.name, zone.Tag, start, err)
+	if err != nil {
+		log.Printf("[!] Ошибка коллектора %s для %s: %v", c.name, zone.Tag, err)
+	}
+	return err
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func boolToString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// statusCode returns the code as a label value, or "unknown" outside the
+// valid 100-599 range, so bad values can't multiply series.
+func statusCode(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(code)
+}
+
+// statusCodeNumber is statusCode for codes decoded as json.Number, which
+// may be empty or non-integer.
+func statusCodeNumber(n json.Number) string {
+	code, err := strconv.Atoi(n.String())
+	if err != nil {
+		return "unknown"
+	}
+	return statusCode(code)
+}
+
+// statusClass maps an HTTP status code to "1xx".."5xx", or "unknown".
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		code  int
+		want  string
+		class string
+	}{
+		{0, "unknown", "unknown"},
+		{99, "unknown", "unknown"},
+		{100, "100", "1xx"},
+		{200, "200", "2xx"},
+		{304, "304", "3xx"},
+		{499, "499", "4xx"},
+		{520, "520", "5xx"},
+		{599, "599", "5xx"},
+		{600, "unknown", "unknown"},
+		{-200, "unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := statusCode(tt.code); got != tt.want {
+			t.Errorf("statusCode(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+		if got := statusClass(tt.code); got != tt.class {
+			t.Errorf("statusClass(%d) = %q, want %q", tt.code, got, tt.class)
+		}
+	}
+}
+
+func TestStatusCodeNumber(t *testing.T) {
+	tests := []struct {
+		n    json.Number
+		want string
+	}{
+		{"", "unknown"},
+		{"404", "404"},
+		{"404.0", "unknown"},
+		{"abc", "unknown"},
+		{"1000", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := statusCodeNumber(tt.n); got != tt.want {
+			t.Errorf("statusCodeNumber(%q) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
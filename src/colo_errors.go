@@ -0,0 +1,123 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// coloLimit keeps the data centers with the most requests per zone,
+	// the rest is summed into __other__; 0 exports every colo
+	coloLimit = 0
+
+	coloRequestsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_colo_requests",
+			Help: "Requests per zone, Cloudflare data center and status class over the adaptive window",
+		},
+		[]string{"zone_tag", "colo", "status_class"},
+	)
+
+	coloErrorRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_colo_error_ratio",
+			Help: "Share of 5xx responses per zone and Cloudflare data center over the adaptive window",
+		},
+		[]string{"zone_tag", "colo"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(coloRequestsMetric)
+	cycleRegistry.MustRegister(coloErrorRatio)
+
+	registerCollector(collector{
+		name:     "colo_errors",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups"},
+		zone:     fetchColoErrors,
+	})
+}
+
+const coloErrorsQuery = `query ($zoneTag: string!, $filter: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      httpRequestsAdaptiveGroups(filter: $filter, limit: 10000) {
+        count
+        dimensions { coloCode edgeResponseStatus }
+      }
+    }
+  }
+}`
+
+func fetchColoErrors(zone Zone) error {
+	z, err := queryZone[struct {
+		HttpRequestsAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				ColoCode           string `json:"coloCode"`
+				EdgeResponseStatus int    `json:"edgeResponseStatus"`
+			} `json:"dimensions"`
+		} `json:"httpRequestsAdaptiveGroups"`
+	}](zone, coloErrorsQuery, map[string]any{"filter": zoneFilter(zone, "httpRequestsAdaptiveGroups", adaptiveFilter())})
+	if err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	coloRequestsMetric.DeletePartialMatch(byZone)
+	coloErrorRatio.DeletePartialMatch(byZone)
+	if z == nil {
+		return nil
+	}
+
+	total := map[string]float64{}
+	for _, g := range z.HttpRequestsAdaptiveGroups {
+		total[g.Dimensions.ColoCode] += g.Count
+	}
+	keep := topColos(total, coloLimit)
+
+	total = map[string]float64{}
+	errors := map[string]float64{}
+	for _, g := range z.HttpRequestsAdaptiveGroups {
+		colo := g.Dimensions.ColoCode
+		if !keep[colo] {
+			colo = topOtherLabel
+		}
+		class := statusClass(g.Dimensions.EdgeResponseStatus)
+		coloRequestsMetric.WithLabelValues(zone.Tag, colo, class).Add(g.Count)
+		total[colo] += g.Count
+		if class == "5xx" {
+			errors[colo] += g.Count
+		}
+	}
+	for colo, count := range total {
+		if count > 0 {
+			coloErrorRatio.WithLabelValues(zone.Tag, colo).Set(errors[colo] / count)
+		}
+	}
+	return nil
+}
+
+// topColos returns the n colos with the most requests, every colo if n is 0.
+func topColos(requests map[string]float64, n int) map[string]bool {
+	colos := make([]string, 0, len(requests))
+	for colo := range requests {
+		colos = append(colos, colo)
+	}
+	sort.Slice(colos, func(i, j int) bool {
+		if requests[colos[i]] != requests[colos[j]] {
+			return requests[colos[i]] > requests[colos[j]]
+		}
+		return colos[i] < colos[j]
+	})
+	if n > 0 && len(colos) > n {
+		colos = colos[:n]
+	}
+	keep := make(map[string]bool, len(colos))
+	for _, colo := range colos {
+		keep[colo] = true
+	}
+	return keep
+}
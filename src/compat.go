@@ -0,0 +1,146 @@
+package main
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// compatMetrics selects a metric naming compatibility mode for /metrics:
+// "lablabs" exposes the traffic metrics under the names and labels of
+// lablabs/cloudflare-exporter, empty keeps the own names.
+var compatMetrics = ""
+
+// compatTarget is a metric of the compatible exporter derived from one of
+// ours. zone_tag becomes zone and only today's date is kept, rename maps
+// our label names to theirs, drop lists labels summed away.
+type compatTarget struct {
+	name   string
+	help   string
+	rename map[string]string
+	drop   []string
+}
+
+var lablabsMetrics = map[string][]compatTarget{
+	"cloudflare_zone_requests_total": {
+		{name: "cloudflare_zone_requests_total", help: "Number of requests for zone"},
+	},
+	"cloudflare_zone_cached_requests_total": {
+		{name: "cloudflare_zone_requests_cached", help: "Number of cached requests for zone"},
+	},
+	"cloudflare_zone_status_code_requests_total": {
+		{name: "cloudflare_zone_requests_status", help: "Number of request for zone per HTTP status", rename: map[string]string{"status_code": "status"}},
+	},
+	"cloudflare_zone_requests_by_country_total": {
+		{name: "cloudflare_zone_requests_country", help: "Number of request for zone per country"},
+	},
+	"cloudflare_zone_bandwidth_bytes_total": {
+		{name: "cloudflare_zone_bandwidth_total", help: "Total bandwidth per zone in bytes"},
+	},
+	"cloudflare_zone_cached_bandwidth_bytes_total": {
+		{name: "cloudflare_zone_bandwidth_cached", help: "Cached bandwidth per zone in bytes"},
+	},
+	"cloudflare_zone_threats_total": {
+		{name: "cloudflare_zone_threats_total", help: "Threats per zone", drop: []string{"type"}},
+		{name: "cloudflare_zone_threats_type", help: "Threats per zone per type"},
+	},
+	"cloudflare_zone_page_views_total": {
+		{name: "cloudflare_zone_pageviews_total", help: "Pageviews per zone"},
+	},
+	"cloudflare_zone_unique_visitors": {
+		{name: "cloudflare_zone_uniques_total", help: "Uniques per zone"},
+	},
+}
+
+// compatGatherer replaces the mapped families by their compatible
+// counterparts. Our daily totals become counters that reset at midnight
+// UTC, which rate() and increase() handle like a restart. It runs after
+// zoneLabelsGatherer, which keys on zone_tag.
+type compatGatherer struct {
+	prometheus.Gatherer
+}
+
+func (g compatGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	if compatMetrics != "lablabs" {
+		return families, err
+	}
+	today := lookbackDates()[0]
+	accountNames := zoneAccountNames()
+
+	out := make([]*dto.MetricFamily, 0, len(families))
+	derived := map[string]*dto.MetricFamily{}
+	series := map[string]*dto.Metric{}
+	for _, mf := range families {
+		targets, ok := lablabsMetrics[mf.GetName()]
+		if !ok {
+			out = append(out, mf)
+			continue
+		}
+		for _, m := range mf.Metric {
+			value := m.GetGauge().GetValue() + m.GetCounter().GetValue()
+			for _, t := range targets {
+				labels, ok := compatLabels(m, t, today, accountNames)
+				if !ok {
+					continue
+				}
+				family, ok := derived[t.name]
+				if !ok {
+					family = &dto.MetricFamily{Name: proto.String(t.name), Help: proto.String(t.help), Type: dto.MetricType_COUNTER.Enum()}
+					derived[t.name] = family
+				}
+				key := t.name
+				for _, lp := range labels {
+					key += "\xff" + lp.GetName() + "\xff" + lp.GetValue()
+				}
+				if s, ok := series[key]; ok {
+					s.Counter.Value = proto.Float64(s.Counter.GetValue() + value)
+					continue
+				}
+				s := &dto.Metric{Label: labels, Counter: &dto.Counter{Value: proto.Float64(value)}}
+				series[key] = s
+				family.Metric = append(family.Metric, s)
+			}
+		}
+	}
+	for _, family := range derived {
+		out = append(out, family)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].GetName() < out[j].GetName() })
+	return out, err
+}
+
+// compatLabels returns the sorted labels of the derived series, false if
+// the series is not today's.
+func compatLabels(m *dto.Metric, t compatTarget, today string, accountNames map[string]string) ([]*dto.LabelPair, bool) {
+	labels := []*dto.LabelPair{}
+	hasAccount := false
+	zone := ""
+	for _, lp := range m.Label {
+		name := lp.GetName()
+		switch {
+		case name == "date":
+			if lp.GetValue() != today {
+				return nil, false
+			}
+			continue
+		case name == "zone_tag":
+			name, zone = "zone", lp.GetValue()
+		case slices.Contains(t.drop, name):
+			continue
+		case t.rename[name] != "":
+			name = t.rename[name]
+		}
+		hasAccount = hasAccount || name == "account"
+		labels = append(labels, &dto.LabelPair{Name: proto.String(name), Value: proto.String(lp.GetValue())})
+	}
+	// the compatible exporter always labels zone series with the account
+	if !hasAccount && accountNames[zone] != "" {
+		labels = append(labels, &dto.LabelPair{Name: proto.String("account"), Value: proto.String(accountNames[zone])})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].GetName() < labels[j].GetName() })
+	return labels, true
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func labelPairs(kv ...string) []*dto.LabelPair {
+	labels := []*dto.LabelPair{}
+	for i := 0; i < len(kv); i += 2 {
+		labels = append(labels, &dto.LabelPair{Name: proto.String(kv[i]), Value: proto.String(kv[i+1])})
+	}
+	return labels
+}
+
+func labelStrings(labels []*dto.LabelPair) []string {
+	out := []string{}
+	for _, lp := range labels {
+		out = append(out, lp.GetName()+"="+lp.GetValue())
+	}
+	return out
+}
+
+func TestCompatLabels(t *testing.T) {
+	accountNames := map[string]string{"example.com": "main"}
+	tests := []struct {
+		name   string
+		target compatTarget
+		labels []*dto.LabelPair
+		want   []string
+		ok     bool
+	}{
+		{
+			name:   "zone_tag becomes zone and the account is added",
+			target: lablabsMetrics["cloudflare_zone_requests_total"][0],
+			labels: labelPairs("date", "2026-10-14", "zone_tag", "example.com"),
+			want:   []string{"account=main", "zone=example.com"},
+			ok:     true,
+		},
+		{
+			name:   "other dates are skipped",
+			target: lablabsMetrics["cloudflare_zone_requests_total"][0],
+			labels: labelPairs("date", "2026-10-13", "zone_tag", "example.com"),
+			ok:     false,
+		},
+		{
+			name:   "status_code is renamed",
+			target: lablabsMetrics["cloudflare_zone_status_code_requests_total"][0],
+			labels: labelPairs("date", "2026-10-14", "status_code", "404", "zone_tag", "example.com"),
+			want:   []string{"account=main", "status=404", "zone=example.com"},
+			ok:     true,
+		},
+		{
+			name:   "dropped labels",
+			target: lablabsMetrics["cloudflare_zone_threats_total"][0],
+			labels: labelPairs("date", "2026-10-14", "type", "bic.ban.unknown", "zone_tag", "example.com"),
+			want:   []string{"account=main", "zone=example.com"},
+			ok:     true,
+		},
+		{
+			name:   "existing account is kept",
+			target: lablabsMetrics["cloudflare_zone_requests_total"][0],
+			labels: labelPairs("account", "other", "date", "2026-10-14", "zone_tag", "example.com"),
+			want:   []string{"account=other", "zone=example.com"},
+			ok:     true,
+		},
+		{
+			name:   "unknown account",
+			target: lablabsMetrics["cloudflare_zone_requests_total"][0],
+			labels: labelPairs("date", "2026-10-14", "zone_tag", "example.org"),
+			want:   []string{"zone=example.org"},
+			ok:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, ok := compatLabels(&dto.Metric{Label: tt.labels}, tt.target, "2026-10-14", accountNames)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if got := labelStrings(labels); ok && !slices.Equal(got, tt.want) {
+				t.Errorf("labels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompatGatherer(t *testing.T) {
+	prev := compatMetrics
+	compatMetrics = "lablabs"
+	defer func() { compatMetrics = prev }()
+
+	today := lookbackDates()[0]
+	gauge := func(value float64, kv ...string) *dto.Metric {
+		return &dto.Metric{Label: labelPairs(kv...), Gauge: &dto.Gauge{Value: proto.Float64(value)}}
+	}
+	families := []*dto.MetricFamily{
+		{
+			Name: proto.String("cloudflare_zone_threats_total"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{
+				gauge(2, "date", today, "type", "a", "zone_tag", "example.com"),
+				gauge(3, "date", today, "type", "b", "zone_tag", "example.com"),
+				gauge(7, "date", "2000-01-01", "type", "a", "zone_tag", "example.com"),
+			},
+		},
+		{Name: proto.String("cloudflare_exporter_up"), Type: dto.MetricType_GAUGE.Enum(), Metric: []*dto.Metric{gauge(1)}},
+	}
+	got, err := compatGatherer{prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return families, nil })}.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, mf := range got {
+		for _, m := range mf.Metric {
+			key := mf.GetName()
+			for _, l := range labelStrings(m.Label) {
+				key += " " + l
+			}
+			values[key] = m.GetGauge().GetValue() + m.GetCounter().GetValue()
+		}
+	}
+	want := map[string]float64{
+		"cloudflare_exporter_up":                               1,
+		"cloudflare_zone_threats_total zone=example.com":       5,
+		"cloudflare_zone_threats_type type=a zone=example.com": 2,
+		"cloudflare_zone_threats_type type=b zone=example.com": 3,
+	}
+	if len(values) != len(want) {
+		t.Errorf("series = %v, want %v", values, want)
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s = %v, want %v", key, values[key], value)
+		}
+	}
+}
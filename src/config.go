@@ -0,0 +1,291 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// option binds one setting to a package variable. Every option can be set,
+// in increasing priority, from the config file (key), the environment
+// (env) and the command line (-flag); env and flag names are derived from
+// the key so no option can miss one of them.
+type option struct {
+	key    string
+	env    string
+	flag   string
+	help   string
+	target any
+	def    string
+}
+
+var (
+	options = []*option{}
+
+	configFile = ""
+	configYAML = ""
+)
+
+func bindOption(target any, key, help string) {
+	options = append(options, &option{
+		key:    key,
+		env:    strings.ToUpper(key),
+		flag:   strings.ReplaceAll(key, "_", "-"),
+		help:   help,
+		target: target,
+		def:    formatOption(target),
+	})
+}
+
+func init() {
+	bindOption(&apiToken, "cloudflare_api_token", "Cloudflare API token")
+	bindOption(&apiTokensList, "cloudflare_api_tokens", "Comma-separated further API tokens, their zones are collected too")
+	bindOption(&zonesInclude, "zones_include", "Comma-separated zone names or glob patterns to collect, empty collects all")
+	bindOption(&zonesExclude, "zones_exclude", "Comma-separated zone names or glob patterns never collected")
+	bindOption(&zoneRefreshInterval, "zone_refresh_interval", "Interval of zone re-discovery, 0 lists zones only at start")
+	bindOption(&collectorsList, "collectors", "Comma-separated list of enabled collectors")
+	bindOption(&scrapeInterval, "scrape_interval", "Pause between collection cycles")
+	bindOption(&listenAddr, "listen_addr", "Address of the HTTP server (/metrics, /status, ...)")
+	bindOption(&collectionMode, "collection_mode", "loop to collect in the background, scrape to query Cloudflare when /metrics is scraped")
+	bindOption(&scrapeCacheTTL, "scrape_cache_ttl", "In scrape mode, how long the result of a cycle is served to following scrapes")
+	bindOption(&verifyPermissions, "verify_permissions", "Probe enabled collectors at startup and report missing token permissions")
+	bindOption(&graphqlTimeout, "graphql_timeout", "Timeout of a single GraphQL query")
+	bindOption(&graphqlMaxResponseBytes, "graphql_max_response_bytes", "GraphQL responses larger than this are aborted")
+	bindOption(&responseCachePath, "response_cache_path", "bbolt file caching the last GraphQL response per zone, query and filter (chunk), empty disables")
+	bindOption(&responseCacheTTL, "response_cache_ttl", "Maximum age of cached GraphQL responses served while Cloudflare is unreachable")
+	bindOption(&restBudgetLimit, "api_budget_rest", "REST API calls allowed per five minutes, fetches wait when it runs low; 0 disables")
+	bindOption(&graphqlBudgetLimit, "api_budget_graphql", "GraphQL queries allowed per five minutes, fetches wait when it runs low; 0 disables")
+	bindOption(&lowPriorityCollectors, "low_priority_collectors", "Comma-separated collectors skipped while the API budget is exhausted or rate limited")
+	bindOption(&scaleSampledCounts, "scale_sampled_counts", "Multiply adaptive counts of origin_status and top_* by the sample interval")
+	bindOption(&replicaLabel, "replica", "Value of a replica label added to every series, for deduplication across sites")
+	bindOption(&regionLabel, "region", "Value of a region label added to every series")
+	bindOption(&warmupPrometheusURL, "warmup_prometheus_url", "Prometheus to restore the last known series from at startup, empty disables")
+	bindOption(&warmupSelector, "warmup_selector", "Series selector of this exporter in the warm-up Prometheus")
+	bindOption(&warmupMaxAge, "warmup_max_age", "Oldest sample the warm-up restores (lookback_delta of the query)")
+	bindOption(&maxRetries, "max_retries", "Repeated attempts of a Cloudflare request after a 429, 5xx gateway error or network failure")
+	bindOption(&retryBaseDelay, "retry_base_delay", "First retry delay, doubled per attempt with jitter; Retry-After takes precedence")
+	bindOption(&groupMetrics, "group_metrics", "Comma-separated zone metrics summed per zone group as cloudflare_zone_group_*")
+	bindOption(&httpConnectTimeout, "http_connect_timeout", "Timeout of TCP connect and TLS handshake to Cloudflare")
+	bindOption(&httpRequestTimeout, "http_request_timeout", "Timeout of a single REST API call, GraphQL uses graphql_timeout")
+	bindOption(&userAgent, "user_agent", "User-Agent of Cloudflare API calls, default: cf-metrics-collector/<version> (instance <replica or host name>)")
+	bindOption(&compatMetrics, "compat_metrics", "Expose the traffic metrics under the names of another exporter: lablabs, empty keeps the own names")
+	bindOption(&kubernetesEvents, "kubernetes_events", "Post zone, collector and token events on the exporter's Pod (in-cluster only)")
+	bindOption(&tokenExpiryWarning, "token_expiry_warning", "Post a TokenExpiring event when an API token expires within this time")
+	bindOption(&analyticsRecheckInterval, "analytics_recheck_interval", "How often the GraphQL datasets of each zone are checked; zones lacking one are skipped by its collectors, 0 polls all zones")
+	bindOption(&burstErrorRatio, "burst_error_ratio", "5xx ratio over burst_window that puts a zone into burst mode, 0 disables")
+	bindOption(&burstThreatRatio, "burst_threat_ratio", "Firewall-mitigated ratio over burst_window that puts a zone into burst mode, 0 disables")
+	bindOption(&burstMinRequests, "burst_min_requests", "Requests over burst_window a zone needs before it can enter burst mode")
+	bindOption(&burstWindow, "burst_window", "Window of the burst triggers and of cloudflare_zone_recent_*")
+	bindOption(&burstInterval, "burst_interval", "Polling interval of zones in burst mode")
+	bindOption(&burstDuration, "burst_duration", "How long a zone stays in burst mode after the last trigger")
+	bindOption(&burstCollectors, "burst_collectors", "Comma-separated zone collectors re-polled in burst mode besides burst")
+	bindOption(&archiveURL, "archive_url", "s3://bucket/prefix to write every cycle's series to as gzipped JSON lines, empty disables")
+	bindOption(&archiveEndpoint, "archive_endpoint", "S3 compatible endpoint, default AWS S3 of archive_region; https://storage.googleapis.com for GCS")
+	bindOption(&archiveRegion, "archive_region", "Region of the archive bucket used in request signing (GCS: auto)")
+	bindOption(&archiveAccessKey, "archive_access_key", "Access key of the archive, default AWS_ACCESS_KEY_ID (GCS: HMAC key)")
+	bindOption(&archiveSecretKey, "archive_secret_key", "Secret key of the archive, default AWS_SECRET_ACCESS_KEY")
+	bindOption(&coloLimit, "colo_limit", "Number of Cloudflare data centers exported per zone by colo_errors, the rest as __other__; 0 for all")
+	bindOption(&fetchConcurrency, "fetch_concurrency", "Zones or accounts fetched in parallel by each collector, within the API budget")
+	bindOption(&shedRateLimited, "shed_rate_limited", "429 responses within five minutes after which low-priority collectors are skipped, 0 only sheds on budget")
+	bindOption(&readinessTimeout, "readiness_timeout", "Time after which /readyz reports ready even if some collectors never succeeded")
+	bindOption(&statePath, "state_path", "JSON file persisting zone changes made through the admin API, empty keeps them in memory")
+	bindOption(&adminToken, "admin_token", "Bearer token of the admin API, empty disables it")
+	bindOption(&redisURL, "redis_url", "Redis shared by replicas: the leader collects and publishes metrics, followers serve them; empty disables")
+	bindOption(&redisKeyPrefix, "redis_key_prefix", "Prefix of the Redis keys")
+	bindOption(&redisLockTTL, "redis_lock_ttl", "Lifetime of the leader lock, renewed every third of it")
+	bindOption(&historyPath, "history_path", "SQLite file storing every collection cycle, empty disables")
+	bindOption(&historyMetrics, "history_metrics", "Comma-separated metrics stored in the history store")
+	bindOption(&historyRetention, "history_retention", "Age after which history samples are deleted, 0 keeps everything")
+	bindOption(&trafficByCountry, "traffic_by_country", "Export traffic requests per client country (high cardinality)")
+	bindOption(&lookbackDays, "lookback_days", "Days, including today, reported by the daily traffic metrics")
+	bindOption(&adaptiveWindow, "adaptive_window", "Time range covered by collectors using adaptive datasets")
+	bindOption(&sloTarget, "slo_target", "Availability SLO target used for error budget gauges")
+	bindOption(&anomalySigma, "anomaly_sigma", "Standard deviations from the baseline reported as a traffic anomaly")
+	bindOption(&anomalyBaselineHours, "anomaly_baseline_hours", "Hours of history used as the anomaly baseline")
+	bindOption(&accessRulesRecentWindow, "ip_access_rules_recent_window", "Age up to which IP access rules count as recently added")
+	bindOption(&healthErrorWeight, "health_error_weight", "Weight of the 5xx ratio in cloudflare_zone_health_score")
+	bindOption(&healthThreatWeight, "health_threat_weight", "Weight of the threat ratio in cloudflare_zone_health_score")
+	bindOption(&healthLatencyWeight, "health_latency_weight", "Weight of the origin p95 latency in cloudflare_zone_health_score")
+	bindOption(&workersIncludedRequests, "billing_workers_included_requests", "Workers requests included in the plan, used for billable usage")
+	bindOption(&customHostnamesLimit, "custom_hostnames_limit", "Number of custom hostnames exported per SaaS zone by custom_hostnames (capped at 50)")
+	bindOption(&topPathsLimit, "top_paths_limit", "Number of paths exported by top_paths (capped at 50)")
+	bindOption(&topReferersLimit, "top_referers_limit", "Number of referer hosts exported by top_referers (capped at 50)")
+	bindOption(&topUserAgentsLimit, "top_user_agents_limit", "Number of user agents queried by top_user_agents (capped at 50)")
+	bindOption(&userAgentGroups, "user_agent_groups", "User agent grouping rules, semicolon-separated group=regexp pairs")
+	bindOption(&botScoreThreshold, "bot_score_threshold", "Bot score below which requests count as automated")
+	bindOption(&dohURL, "doh_url", "DNS over HTTPS JSON endpoint used for delegation checks")
+	bindOption(&logpushEnabled, "logpush_enabled", "Accept Logpush HTTP destination batches")
+	bindOption(&logpushPath, "logpush_path", "Path of the Logpush receiver")
+	bindOption(&logpushAuth, "logpush_authorization", "Expected Authorization header of Logpush requests, required with logpush_enabled")
+	bindOption(&logpushMaxPaths, "logpush_max_paths", "Distinct paths counted per zone by the Logpush receiver")
+}
+
+func formatOption(target any) string {
+	switch t := target.(type) {
+	case *string:
+		return *t
+	case *int:
+		return strconv.Itoa(*t)
+	case *bool:
+		return strconv.FormatBool(*t)
+	case *float64:
+		return strconv.FormatFloat(*t, 'g', -1, 64)
+	case *time.Duration:
+		return t.String()
+	}
+	panic(fmt.Sprintf("unsupported option type %T", target))
+}
+
+func (o *option) set(value string) error {
+	var err error
+	switch t := o.target.(type) {
+	case *string:
+		*t = value
+	case *int:
+		*t, err = strconv.Atoi(value)
+	case *bool:
+		*t, err = strconv.ParseBool(value)
+	case *float64:
+		*t, err = strconv.ParseFloat(value, 64)
+	case *time.Duration:
+		*t, err = time.ParseDuration(value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: invalid value %q: %v", o.key, value, err)
+	}
+	return nil
+}
+
+// loadConfig applies the config file, environment and command line to all
+// bound options.
+func loadConfig(args []string) error {
+	fs := flag.NewFlagSet("cf-metrics-collector", flag.ExitOnError)
+	fs.StringVar(&configFile, "config-file", os.Getenv("CONFIG_FILE"), "YAML config file (env CONFIG_FILE)")
+	fs.StringVar(&configYAML, "config-yaml", os.Getenv("CONFIG_YAML"), "Inline YAML config, merged over the config file (env CONFIG_YAML)")
+	for _, o := range options {
+		fs.String(o.flag, o.def, fmt.Sprintf("%s (env %s)", o.help, o.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	sources := []string{}
+	if configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, string(data))
+	}
+	if configYAML != "" {
+		sources = append(sources, configYAML)
+	}
+	for _, src := range sources {
+		if err := applyConfigYAML([]byte(src)); err != nil {
+			return err
+		}
+	}
+
+	for _, o := range options {
+		if v, ok := os.LookupEnv(o.env); ok && v != "" {
+			if err := o.set(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, o := range options {
+			if o.flag == f.Name && flagErr == nil {
+				flagErr = o.set(f.Value.String())
+			}
+		}
+	})
+	return flagErr
+}
+
+func applyConfigYAML(data []byte) error {
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	raw := map[string]any{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, o := range options {
+		v, ok := raw[o.key]
+		if !ok {
+			continue
+		}
+		value := fmt.Sprint(v)
+		if list, ok := v.([]any); ok {
+			items := make([]string, 0, len(list))
+			for _, item := range list {
+				items = append(items, fmt.Sprint(item))
+			}
+			value = strings.Join(items, ",")
+		}
+		if err := o.set(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileConfig is the structured part of the YAML configuration.
+type fileConfig struct {
+	// Zones holds per-zone settings keyed by zone name or glob pattern.
+	Zones map[string]zoneConfig `yaml:"zones"`
+	// Tenants holds the settings of /metrics/tenants/{tenant} endpoints.
+	Tenants map[string]tenantConfig `yaml:"tenants"`
+	// Blackouts are periods during which no collection cycle starts.
+	Blackouts []blackoutWindow `yaml:"blackouts"`
+}
+
+type zoneConfig struct {
+	// Filters are extra GraphQL filters per dataset, combined with the
+	// collector's own filter.
+	Filters map[string]map[string]any `yaml:"filters"`
+	// SLOTarget overrides SLO_TARGET for the zone.
+	SLOTarget *float64 `yaml:"slo_target"`
+	// Labels are added to every series of the zone, e.g. team or owner
+	// for Alertmanager routing.
+	Labels map[string]string `yaml:"labels"`
+	// Tenant is the tenant whose /metrics/tenants endpoint serves the zone.
+	Tenant string `yaml:"tenant"`
+	// Paused zones stay discovered but are not queried by zone collectors.
+	Paused bool `yaml:"paused"`
+	// Priority orders zone fetches, higher first, so that the important
+	// zones are collected before the API budget runs low.
+	Priority int `yaml:"priority"`
+	// Groups are the zone groups the zone is summed into, see groupMetrics.
+	Groups []string `yaml:"groups"`
+}
+
+var config = fileConfig{}
+
+// zoneConfigs returns the settings of every zones entry matching the zone,
+// in pattern order.
+func zoneConfigs(zone Zone) []zoneConfig {
+	patterns := make([]string, 0, len(config.Zones))
+	for pattern := range config.Zones {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	matched := []zoneConfig{}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, zone.Tag); ok {
+			matched = append(matched, config.Zones[pattern])
+		}
+	}
+	return matched
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var responseBytesMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cloudflare_zone_response_bytes",
+		Help: "Edge response bytes per zone by content type and status class over the adaptive window",
+	},
+	[]string{"zone_tag", "content_type", "status_class"},
+)
+
+func init() {
+	cycleRegistry.MustRegister(responseBytesMetric)
+
+	registerCollector(collector{
+		name:     "content_bytes",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups"},
+		zone:     fetchResponseBytes,
+	})
+}
+
+const responseBytesQuery = `query ($zoneTag: string!, $filter: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      httpRequestsAdaptiveGroups(filter: $filter, limit: 5000) {
+        sum { edgeResponseBytes }
+        dimensions { edgeResponseContentTypeName edgeResponseStatus }
+      }
+    }
+  }
+}`
+
+func fetchResponseBytes(zone Zone) error {
+	z, err := queryZone[struct {
+		HttpRequestsAdaptiveGroups []struct {
+			Sum struct {
+				EdgeResponseBytes float64 `json:"edgeResponseBytes"`
+			} `json:"sum"`
+			Dimensions struct {
+				EdgeResponseContentTypeName string `json:"edgeResponseContentTypeName"`
+				EdgeResponseStatus          int    `json:"edgeResponseStatus"`
+			} `json:"dimensions"`
+		} `json:"httpRequestsAdaptiveGroups"`
+	}](zone, responseBytesQuery, map[string]any{"filter": zoneFilter(zone, "httpRequestsAdaptiveGroups", adaptiveFilter())})
+	if err != nil {
+		return err
+	}
+
+	responseBytesMetric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag})
+	if z == nil {
+		return nil
+	}
+	for _, g := range z.HttpRequestsAdaptiveGroups {
+		contentType := g.Dimensions.EdgeResponseContentTypeName
+		if contentType == "" {
+			contentType = "empty"
+		}
+		responseBytesMetric.WithLabelValues(zone.Tag, contentType, statusClass(g.Dimensions.EdgeResponseStatus)).Add(g.Sum.EdgeResponseBytes)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	customHostnamesLimit = 20
+
+	customHostnamesMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_custom_hostnames",
+			Help: "Custom hostnames (SSL for SaaS) configured on the zone",
+		},
+		[]string{"zone_tag"},
+	)
+
+	customHostnameRequestsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_custom_hostname_requests",
+			Help: "Requests for the top N custom hostnames per SaaS zone over the adaptive window",
+		},
+		[]string{"zone_tag", "hostname"},
+	)
+
+	customHostnameErrorsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_custom_hostname_errors",
+			Help: "Requests answered with 5xx for the top N custom hostnames per SaaS zone over the adaptive window",
+		},
+		[]string{"zone_tag", "hostname"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(customHostnamesMetric)
+	cycleRegistry.MustRegister(customHostnameRequestsMetric)
+	cycleRegistry.MustRegister(customHostnameErrorsMetric)
+
+	registerCollector(collector{
+		name:     "custom_hostnames",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups"},
+		zone:     fetchCustomHostnames,
+	})
+}
+
+const customHostnamesQuery = `query ($zoneTag: string!, $filter: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!, $errors: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      total: httpRequestsAdaptiveGroups(filter: $filter, limit: 1) { count avg { sampleInterval } }
+      totalErrors: httpRequestsAdaptiveGroups(filter: $errors, limit: 1) { count avg { sampleInterval } }
+      top: httpRequestsAdaptiveGroups(filter: $filter, limit: %d, orderBy: [count_DESC]) {
+        count
+        avg { sampleInterval }
+        dimensions { clientRequestHTTPHost }
+      }
+    }
+  }
+}`
+
+const customHostnameErrorsQuery = `query ($zoneTag: string!, $filter: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      httpRequestsAdaptiveGroups(filter: $filter, limit: %d) {
+        count
+        avg { sampleInterval }
+        dimensions { clientRequestHTTPHost }
+      }
+    }
+  }
+}`
+
+type hostGroup struct {
+	sampledGroup
+	Count      float64 `json:"count"`
+	Dimensions struct {
+		ClientRequestHTTPHost string `json:"clientRequestHTTPHost"`
+	} `json:"dimensions"`
+}
+
+// customHostnameLabel is the hostname label value of a request host.
+func customHostnameLabel(host string) string {
+	return truncateLabel(strings.ToLower(host))
+}
+
+// fetchCustomHostnames exports the traffic of the zone's busiest custom
+// hostnames. Zones without custom hostnames only get the count; requests
+// for the zone's own name and its subdomains are not customer traffic.
+func fetchCustomHostnames(zone Zone) error {
+	info, err := cfGet("/zones/"+zone.ID+"/custom_hostnames?per_page=5", nil)
+	if err != nil {
+		return err
+	}
+	customHostnamesMetric.WithLabelValues(zone.Tag).Set(float64(info.TotalCount))
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	if info.TotalCount == 0 {
+		customHostnameRequestsMetric.DeletePartialMatch(byZone)
+		customHostnameErrorsMetric.DeletePartialMatch(byZone)
+		return nil
+	}
+
+	n := min(max(customHostnamesLimit, 1), maxTopN)
+	filter := adaptiveFilter()
+	filter["clientRequestHTTPHost_neq"] = zone.Tag
+	filter["clientRequestHTTPHost_notlike"] = "%." + zone.Tag
+	errorsFilter := map[string]any{"edgeResponseStatus_geq": 500}
+	for k, v := range filter {
+		errorsFilter[k] = v
+	}
+	z, err := queryZone[struct {
+		Total       []hostGroup `json:"total"`
+		TotalErrors []hostGroup `json:"totalErrors"`
+		Top         []hostGroup `json:"top"`
+	}](zone, fmt.Sprintf(customHostnamesQuery, n), map[string]any{
+		"filter": zoneFilter(zone, "httpRequestsAdaptiveGroups", filter),
+		"errors": zoneFilter(zone, "httpRequestsAdaptiveGroups", errorsFilter),
+	})
+	if err != nil {
+		return err
+	}
+
+	customHostnameRequestsMetric.DeletePartialMatch(byZone)
+	customHostnameErrorsMetric.DeletePartialMatch(byZone)
+	if z == nil || len(z.Top) == 0 {
+		return nil
+	}
+
+	total, totalErrors := 0.0, 0.0
+	for _, g := range z.Total {
+		total += sampledCount(g.Count, g.sampledGroup)
+	}
+	for _, g := range z.TotalErrors {
+		totalErrors += sampledCount(g.Count, g.sampledGroup)
+	}
+	hosts := make([]string, 0, len(z.Top))
+	covered := 0.0
+	for _, g := range z.Top {
+		count := sampledCount(g.Count, g.sampledGroup)
+		hosts = append(hosts, g.Dimensions.ClientRequestHTTPHost)
+		customHostnameRequestsMetric.WithLabelValues(zone.Tag, customHostnameLabel(g.Dimensions.ClientRequestHTTPHost)).Add(count)
+		customHostnameErrorsMetric.WithLabelValues(zone.Tag, customHostnameLabel(g.Dimensions.ClientRequestHTTPHost)).Add(0)
+		covered += count
+	}
+	customHostnameRequestsMetric.WithLabelValues(zone.Tag, topOtherLabel).Set(max(0, total-covered))
+
+	// errors of exactly the top hosts, an error-heavy host outside the top
+	// only shows in __other__
+	errorsFilter["clientRequestHTTPHost_in"] = hosts
+	e, err := queryZone[struct {
+		HttpRequestsAdaptiveGroups []hostGroup `json:"httpRequestsAdaptiveGroups"`
+	}](zone, fmt.Sprintf(customHostnameErrorsQuery, n), map[string]any{
+		"filter": zoneFilter(zone, "httpRequestsAdaptiveGroups", errorsFilter),
+	})
+	if err != nil {
+		return err
+	}
+	coveredErrors := 0.0
+	if e != nil {
+		for _, g := range e.HttpRequestsAdaptiveGroups {
+			count := sampledCount(g.Count, g.sampledGroup)
+			customHostnameErrorsMetric.WithLabelValues(zone.Tag, customHostnameLabel(g.Dimensions.ClientRequestHTTPHost)).Add(count)
+			coveredErrors += count
+		}
+	}
+	customHostnameErrorsMetric.WithLabelValues(zone.Tag, topOtherLabel).Set(max(0, totalErrors-coveredErrors))
+	return nil
+}
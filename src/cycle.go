@@ -0,0 +1,168 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// cycleRegistry holds the metrics written by collectors. They are exposed
+// from a snapshot taken at the end of each cycle, so a scrape never sees
+// a mix of two cycles.
+var cycleRegistry = &metricsRegistry{Registry: prometheus.NewRegistry()}
+
+// metricsRegistry remembers the registered vectors to delete the series of
+// a zone or account from all of them.
+type metricsRegistry struct {
+	*prometheus.Registry
+	vecs []prometheus.Collector
+}
+
+func (r *metricsRegistry) MustRegister(cs ...prometheus.Collector) {
+	r.Registry.MustRegister(cs...)
+	r.vecs = append(r.vecs, cs...)
+}
+
+// deletePartialMatch removes the matching series of every registered vector.
+func (r *metricsRegistry) deletePartialMatch(labels prometheus.Labels) int {
+	deleted := 0
+	for _, c := range r.vecs {
+		if vec, ok := c.(interface {
+			DeletePartialMatch(prometheus.Labels) int
+		}); ok {
+			deleted += vec.DeletePartialMatch(labels)
+		}
+	}
+	return deleted
+}
+
+var cycleSnapshot atomic.Pointer[[]*dto.MetricFamily]
+
+// cycleGatherer serves the last cycle snapshot together with the live
+// exporter metrics of the default registry.
+var cycleGatherer = prometheus.Gatherers{snapshotGatherer{}, prometheus.DefaultGatherer}
+
+// exposedGatherer backs /metrics: cycleGatherer, or the pull collector in
+// scrape mode.
+var exposedGatherer prometheus.Gatherer = cycleGatherer
+
+// scrapeInterval is the pause between the end of one collection cycle and
+// the start of the next.
+var scrapeInterval = 5 * time.Minute
+
+var (
+	// collectMutex serializes the cycles and the burst passes in between.
+	collectMutex = &sync.Mutex{}
+	cycleMutex   = &sync.Mutex{}
+	cycleStart   time.Time
+	cycleRunning atomic.Bool
+	// fetchesPending counts the zone and account fetches of the running
+	// cycle that have not finished yet.
+	fetchesPending atomic.Int64
+
+	cycleDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudflare_exporter_cycle_duration_seconds",
+		Help: "Duration of the last complete collection cycle",
+	})
+
+	cycleInterval = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudflare_exporter_cycle_interval_seconds",
+		Help: "Configured interval between collection cycles",
+	})
+
+	cycleLastCompleted = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudflare_exporter_cycle_last_completed_timestamp_seconds",
+		Help: "End of the last complete collection cycle",
+	})
+
+	cycleOverruns = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cloudflare_exporter_cycle_overruns_total",
+		Help: "Collection cycles that took longer than the configured interval",
+	})
+
+	fetchesPendingMetric = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cloudflare_exporter_fetches_pending",
+		Help: "Zone and account fetches of the running cycle not done yet",
+	}, func() float64 { return float64(fetchesPending.Load()) })
+
+	fetchQueueLag = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cloudflare_exporter_fetch_queue_lag_seconds",
+		Help: "Time the oldest pending fetch of the running cycle has been waiting, 0 when idle",
+	}, func() float64 {
+		if fetchesPending.Load() == 0 {
+			return 0
+		}
+		cycleMutex.Lock()
+		defer cycleMutex.Unlock()
+		return time.Since(cycleStart).Seconds()
+	})
+)
+
+func init() {
+	prometheus.MustRegister(cycleDuration)
+	prometheus.MustRegister(cycleInterval)
+	prometheus.MustRegister(cycleLastCompleted)
+	prometheus.MustRegister(cycleOverruns)
+	prometheus.MustRegister(fetchesPendingMetric)
+	prometheus.MustRegister(fetchQueueLag)
+}
+
+// swapCycle replaces the exposed snapshot with the current state of
+// cycleRegistry.
+func swapCycle() {
+	families, err := cycleRegistry.Gather()
+	if err != nil {
+		log.Println("[!] Ошибка сбора метрик цикла:", err)
+	}
+	cycleSnapshot.Store(&families)
+}
+
+type snapshotGatherer struct{}
+
+// Gather returns copies, the wrapping gatherers modify the families.
+func (snapshotGatherer) Gather() ([]*dto.MetricFamily, error) {
+	snapshot := cycleSnapshot.Load()
+	if snapshot == nil {
+		return nil, nil
+	}
+	families := make([]*dto.MetricFamily, 0, len(*snapshot))
+	for _, mf := range *snapshot {
+		families = append(families, proto.Clone(mf).(*dto.MetricFamily))
+	}
+	return families, nil
+}
+
+// runCycle collects all zones once and updates the derived stores.
+func runCycle() {
+	collectMutex.Lock()
+	defer collectMutex.Unlock()
+	start := time.Now()
+	cycleMutex.Lock()
+	cycleStart = start
+	cycleMutex.Unlock()
+	cycleRunning.Store(true)
+	defer cycleRunning.Store(false)
+
+	collectAll()
+	pruneStaleSeries()
+	recordHistory()
+	updateRollups()
+	swapCycle()
+	if snapshot := cycleSnapshot.Load(); snapshot != nil {
+		archiveCycle(*snapshot, time.Now())
+	}
+
+	duration := time.Since(start)
+	cycleDuration.Set(duration.Seconds())
+	cycleInterval.Set(scrapeInterval.Seconds())
+	cycleLastCompleted.Set(float64(time.Now().Unix()))
+	if duration > scrapeInterval {
+		cycleOverruns.Inc()
+		log.Printf("[!] Цикл сбора занял %s, дольше интервала %s", duration.Round(time.Second), scrapeInterval)
+	}
+}
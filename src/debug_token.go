@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+)
+
+// maskSecret keeps only the edges of a secret for display.
+func maskSecret(s string) string {
+	if len(s) <= 8 {
+		return "****"
+	}
+	return s[:4] + "****" + s[len(s)-4:]
+}
+
+// tokenDebugHandler serves /debug/token: live token status, its policies
+// (if the token may read itself), dataset entitlements and the startup
+// collector checks.
+func tokenDebugHandler(w http.ResponseWriter, r *http.Request) {
+	type policy struct {
+		Effect           string   `json:"effect"`
+		PermissionGroups []string `json:"permission_groups"`
+		Resources        []string `json:"resources"`
+	}
+	report := struct {
+		Token       string                    `json:"token"`
+		ID          string                    `json:"id,omitempty"`
+		Status      string                    `json:"status,omitempty"`
+		ExpiresOn   *time.Time                `json:"expires_on,omitempty"`
+		Error       string                    `json:"error,omitempty"`
+		Policies    []policy                  `json:"policies,omitempty"`
+		PolicyError string                    `json:"policies_error,omitempty"`
+		Datasets    map[string]bool           `json:"datasets,omitempty"`
+		DatasetErr  string                    `json:"datasets_error,omitempty"`
+		Collectors  map[string]collectorCheck `json:"collectors"`
+	}{
+		Token:    maskSecret(apiToken),
+		Datasets: map[string]bool{},
+	}
+
+	status, err := verifyAPIToken(tokenFor("", nil))
+	report.ID = status.ID
+	report.Status = status.Status
+	if !status.ExpiresOn.IsZero() {
+		report.ExpiresOn = &status.ExpiresOn
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	if status.ID != "" {
+		var details struct {
+			Policies []struct {
+				Effect           string            `json:"effect"`
+				Resources        map[string]string `json:"resources"`
+				PermissionGroups []struct {
+					Name string `json:"name"`
+				} `json:"permission_groups"`
+			} `json:"policies"`
+		}
+		if _, err := cfGet("/user/tokens/"+status.ID, &details); err != nil {
+			report.PolicyError = err.Error()
+		}
+		for _, p := range details.Policies {
+			item := policy{Effect: p.Effect}
+			for _, g := range p.PermissionGroups {
+				item.PermissionGroups = append(item.PermissionGroups, g.Name)
+			}
+			for res := range p.Resources {
+				item.Resources = append(item.Resources, res)
+			}
+			sort.Strings(item.Resources)
+			report.Policies = append(report.Policies, item)
+		}
+	}
+
+	// the dataset checks are live queries, keep zonesMutex out of them
+	zonesMutex.RLock()
+	zones := slices.Clone(zones)
+	accounts := slices.Clone(accounts)
+	zonesMutex.RUnlock()
+
+	zoneDatasets, accountDatasets := map[string]bool{}, map[string]bool{}
+	for _, c := range collectors {
+		if !enabledCollectors[c.name] {
+			continue
+		}
+		for _, d := range c.datasets {
+			if c.zone != nil {
+				zoneDatasets[d] = true
+			} else {
+				accountDatasets[d] = true
+			}
+		}
+	}
+	check := func(datasets map[string]bool, zone *Zone, account *Account) {
+		list := make([]string, 0, len(datasets))
+		for d := range datasets {
+			list = append(list, d)
+			report.Datasets[d] = true
+		}
+		if len(list) == 0 {
+			return
+		}
+		waitForBudget()
+		missing, err := datasetSettings(list, zone, account)
+		if err != nil {
+			report.DatasetErr = err.Error()
+		}
+		for _, d := range missing {
+			report.Datasets[d] = false
+		}
+	}
+	if len(zones) > 0 {
+		check(zoneDatasets, &zones[0], nil)
+	}
+	if len(accounts) > 0 {
+		check(accountDatasets, nil, &accounts[0])
+	}
+
+	collectorChecksMutex.RLock()
+	report.Collectors = collectorChecks
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	collectorChecksMutex.RUnlock()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dnsTypeNS is the NS resource record type.
+const dnsTypeNS = 2
+
+var (
+	zoneStatusMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_status",
+			Help: "1 for the current Cloudflare status of every zone of the account, including pending ones",
+		},
+		[]string{"zone_tag", "status"},
+	)
+
+	zoneNameserverInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_nameserver_info",
+			Help: "Nameservers assigned by Cloudflare and actually delegated in DNS",
+		},
+		[]string{"zone_tag", "nameserver", "source"},
+	)
+
+	zoneDelegationOK = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_delegation_ok",
+			Help: "1 if the delegated nameservers match the nameservers assigned by Cloudflare",
+		},
+		[]string{"zone_tag"},
+	)
+
+	// delegationZones are the zone names reported per account in the last
+	// run, to delete the series of zones that left the account
+	delegationZones      = map[string]map[string]bool{}
+	delegationZonesMutex = &sync.Mutex{}
+)
+
+func init() {
+	cycleRegistry.MustRegister(zoneStatusMetric)
+	cycleRegistry.MustRegister(zoneNameserverInfo)
+	cycleRegistry.MustRegister(zoneDelegationOK)
+
+	registerCollector(collector{
+		name:    "delegation",
+		scope:   "Zone",
+		account: fetchDelegation,
+	})
+}
+
+// fetchDelegation lists all zones of the account, not only the active ones
+// that are discovered for collection, since delegation problems keep zones
+// pending.
+func fetchDelegation(account Account) error {
+	allZones, err := cfGetAll[struct {
+		Name        string   `json:"name"`
+		Status      string   `json:"status"`
+		Type        string   `json:"type"`
+		NameServers []string `json:"name_servers"`
+	}]("/zones?account.id="+url.QueryEscape(account.ID), 50)
+	if err != nil {
+		return err
+	}
+
+	current := map[string]bool{}
+	failed := 0
+	for _, zone := range allZones {
+		current[zone.Name] = true
+		byZone := prometheus.Labels{"zone_tag": zone.Name}
+		zoneStatusMetric.DeletePartialMatch(byZone)
+		zoneStatusMetric.WithLabelValues(zone.Name, zone.Status).Set(1)
+		zoneNameserverInfo.DeletePartialMatch(byZone)
+		zoneDelegationOK.DeletePartialMatch(byZone)
+		// partial (CNAME setup) zones are not delegated to Cloudflare
+		if zone.Type == "partial" {
+			continue
+		}
+
+		actual, err := lookupNS(zone.Name)
+		if err != nil {
+			failed++
+			log.Printf("[!] Ошибка DNS-запроса NS для зоны %s: %v", zone.Name, err)
+			continue
+		}
+		assigned := normalizeNameservers(zone.NameServers)
+
+		for _, ns := range assigned {
+			zoneNameserverInfo.WithLabelValues(zone.Name, ns, "assigned").Set(1)
+		}
+		for _, ns := range actual {
+			zoneNameserverInfo.WithLabelValues(zone.Name, ns, "actual").Set(1)
+		}
+		zoneDelegationOK.WithLabelValues(zone.Name).Set(boolToFloat(len(actual) > 0 && slices.Equal(assigned, actual)))
+	}
+
+	delegationZonesMutex.Lock()
+	for name := range delegationZones[account.Name] {
+		if !current[name] {
+			forgetDelegationZone(name)
+		}
+	}
+	delegationZones[account.Name] = current
+	delegationZonesMutex.Unlock()
+
+	if failed > 0 {
+		return fmt.Errorf("NS lookup failed for %d of %d zones", failed, len(allZones))
+	}
+	return nil
+}
+
+func forgetDelegationZone(name string) {
+	byZone := prometheus.Labels{"zone_tag": name}
+	zoneStatusMetric.DeletePartialMatch(byZone)
+	zoneNameserverInfo.DeletePartialMatch(byZone)
+	zoneDelegationOK.DeletePartialMatch(byZone)
+}
+
+// forgetDelegation drops the series of every zone of a removed account
+// target.
+func forgetDelegation(target string) {
+	name, ok := strings.CutPrefix(target, "account:")
+	if !ok {
+		return
+	}
+	delegationZonesMutex.Lock()
+	defer delegationZonesMutex.Unlock()
+	for zone := range delegationZones[name] {
+		forgetDelegationZone(zone)
+	}
+	delete(delegationZones, name)
+}
+
+func normalizeNameservers(list []string) []string {
+	out := make([]string, 0, len(list))
+	for _, ns := range list {
+		out = append(out, strings.TrimSuffix(strings.ToLower(ns), "."))
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// lookupNS resolves the delegated nameservers of name.
+func lookupNS(name string) ([]string, error) {
+	answers, err := dohQuery(name, "NS")
+	if err != nil {
+		return nil, err
+	}
+	ns := []string{}
+	for _, rr := range answers {
+		if rr.Type == dnsTypeNS {
+			ns = append(ns, rr.Data)
+		}
+	}
+	return normalizeNameservers(ns), nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var dlpMatchesMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cloudflare_zero_trust_dlp_matches",
+		Help: "Zero Trust DLP profile matches by profile and action over the adaptive window",
+	},
+	[]string{"account", "profile", "action"},
+)
+
+func init() {
+	cycleRegistry.MustRegister(dlpMatchesMetric)
+
+	registerCollector(collector{
+		name:     "dlp",
+		scope:    "Zero Trust",
+		datasets: []string{"gatewayL7RequestsAdaptiveGroups"},
+		account:  fetchDLPMatches,
+	})
+}
+
+const dlpQuery = `query ($accountTag: string!, $filter: AccountGatewayL7RequestsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    accounts(filter: { accountTag: $accountTag }) {
+      gatewayL7RequestsAdaptiveGroups(filter: $filter, limit: 1000) {
+        count
+        dimensions { dlpProfileId action }
+      }
+    }
+  }
+}`
+
+func fetchDLPMatches(account Account) error {
+	var profiles []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if _, err := cfGet("/accounts/"+account.ID+"/dlp/profiles", &profiles); err != nil {
+		return err
+	}
+	names := map[string]string{}
+	for _, p := range profiles {
+		names[p.ID] = p.Name
+	}
+
+	filter := adaptiveFilter()
+	filter["dlpProfileId_neq"] = ""
+	a, err := queryAccount[struct {
+		GatewayL7RequestsAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				DlpProfileID string `json:"dlpProfileId"`
+				Action       string `json:"action"`
+			} `json:"dimensions"`
+		} `json:"gatewayL7RequestsAdaptiveGroups"`
+	}](account, dlpQuery, map[string]any{"filter": filter})
+	if err != nil {
+		return err
+	}
+
+	dlpMatchesMetric.DeletePartialMatch(prometheus.Labels{"account": account.Name})
+	if a == nil {
+		return nil
+	}
+	for _, g := range a.GatewayL7RequestsAdaptiveGroups {
+		profile := names[g.Dimensions.DlpProfileID]
+		if profile == "" {
+			profile = g.Dimensions.DlpProfileID
+		}
+		dlpMatchesMetric.WithLabelValues(account.Name, profile, g.Dimensions.Action).Add(g.Count)
+	}
+	return nil
+}
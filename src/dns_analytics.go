@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var dnsQueriesMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cloudflare_zone_dns_queries_total",
+		Help: "Authoritative DNS queries per zone and day by query type and response code",
+	},
+	[]string{"zone_tag", "query_type", "response_code", "date"},
+)
+
+func init() {
+	cycleRegistry.MustRegister(dnsQueriesMetric)
+
+	registerCollector(collector{
+		name:     "dns",
+		scope:    "Zone Analytics",
+		datasets: []string{"dnsAnalyticsAdaptiveGroups"},
+		zone:     fetchDNSQueries,
+	})
+}
+
+const dnsQueriesQuery = `query ($zoneTag: string!, $filter: ZoneDnsAnalyticsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      dnsAnalyticsAdaptiveGroups(filter: $filter, limit: 10000) {
+        count
+        dimensions { date queryType responseCode }
+      }
+    }
+  }
+}`
+
+// fetchDNSQueries covers the same LOOKBACK_DAYS dates as the traffic
+// metrics; dates leaving the window disappear with the next fetch.
+func fetchDNSQueries(zone Zone) error {
+	dates := lookbackDates()
+	filter := map[string]any{"date_geq": dates[len(dates)-1], "date_leq": dates[0]}
+	z, err := queryZone[struct {
+		DnsAnalyticsAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				Date         string `json:"date"`
+				QueryType    string `json:"queryType"`
+				ResponseCode string `json:"responseCode"`
+			} `json:"dimensions"`
+		} `json:"dnsAnalyticsAdaptiveGroups"`
+	}](zone, dnsQueriesQuery, map[string]any{"filter": zoneFilter(zone, "dnsAnalyticsAdaptiveGroups", filter)})
+	if err != nil {
+		return err
+	}
+
+	dnsQueriesMetric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag})
+	if z == nil {
+		return nil
+	}
+	for _, g := range z.DnsAnalyticsAdaptiveGroups {
+		d := g.Dimensions
+		dnsQueriesMetric.WithLabelValues(zone.Tag, d.QueryType, d.ResponseCode, d.Date).Add(g.Count)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dnsTypeDS is the DS resource record type.
+const dnsTypeDS = 43
+
+var (
+	dohURL = "https://cloudflare-dns.com/dns-query"
+
+	dnssecStates = []string{"active", "pending", "disabled", "pending-disabled", "error"}
+
+	dnssecStatusMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_dnssec_status",
+			Help: "1 for the current DNSSEC state of the zone",
+		},
+		[]string{"zone_tag", "status"},
+	)
+
+	dnssecDSPresentMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_dnssec_ds_present",
+			Help: "1 if a DS record for the zone is published in the parent zone (DNS over HTTPS lookup)",
+		},
+		[]string{"zone_tag"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(dnssecStatusMetric)
+	cycleRegistry.MustRegister(dnssecDSPresentMetric)
+
+	registerCollector(collector{
+		name:  "dnssec",
+		scope: "DNS",
+		zone:  fetchDNSSEC,
+	})
+}
+
+func fetchDNSSEC(zone Zone) error {
+	var dnssec struct {
+		Status string `json:"status"`
+	}
+	if _, err := cfGet("/zones/"+zone.ID+"/dnssec", &dnssec); err != nil {
+		return err
+	}
+	for _, state := range dnssecStates {
+		dnssecStatusMetric.WithLabelValues(zone.Tag, state).Set(boolToFloat(dnssec.Status == state))
+	}
+
+	present, err := lookupDS(zone.Tag)
+	if err != nil {
+		return err
+	}
+	dnssecDSPresentMetric.WithLabelValues(zone.Tag).Set(boolToFloat(present))
+	return nil
+}
+
+type dohAnswer struct {
+	Type int    `json:"type"`
+	Data string `json:"data"`
+}
+
+// dohQuery resolves name through the DoH JSON API.
+func dohQuery(name, qtype string) ([]dohAnswer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), httpRequestTimeout)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", dohURL+"?type="+qtype+"&name="+url.QueryEscape(name), nil)
+	req.Header.Set("Accept", "application/dns-json")
+
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH %s %s: status %d", qtype, name, resp.StatusCode)
+	}
+
+	var result struct {
+		Answer []dohAnswer `json:"Answer"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Answer, nil
+}
+
+// lookupDS reports whether the parent zone publishes a DS record for name.
+func lookupDS(name string) (bool, error) {
+	answers, err := dohQuery(name, "DS")
+	if err != nil {
+		return false, err
+	}
+	for _, rr := range answers {
+		if rr.Type == dnsTypeDS {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// exportQuery takes the dataset, its filter type and the time dimension.
+const exportQuery = `query ($zoneTag: string!, $filter: %[2]s!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      %[1]s(filter: $filter, limit: 10000, orderBy: [%[3]s_ASC]) {
+        sum { requests cachedRequests bytes cachedBytes threats pageViews }
+        uniq { uniques }
+        dimensions { %[3]s }
+      }
+    }
+  }
+}`
+
+type exportGranularity struct {
+	dataset, filterType, dimension string
+	// chunk is the time range of one query
+	chunk time.Duration
+	// geq and lt are the filter fields of the time dimension
+	geq, lt string
+	format  string
+}
+
+var exportGranularities = map[string]exportGranularity{
+	"day": {
+		dataset: "httpRequests1dGroups", filterType: "ZoneHttpRequests1dGroupsFilter_InputObject", dimension: "date",
+		chunk: dailyChunkDays * 24 * time.Hour, geq: "date_geq", lt: "date_lt", format: "2006-01-02",
+	},
+	"hour": {
+		dataset: "httpRequests1hGroups", filterType: "ZoneHttpRequests1hGroupsFilter_InputObject", dimension: "datetime",
+		chunk: 3 * 24 * time.Hour, geq: "datetime_geq", lt: "datetime_lt", format: time.RFC3339,
+	},
+}
+
+type exportRow struct {
+	Sum struct {
+		Requests       float64 `json:"requests"`
+		CachedRequests float64 `json:"cachedRequests"`
+		Bytes          float64 `json:"bytes"`
+		CachedBytes    float64 `json:"cachedBytes"`
+		Threats        float64 `json:"threats"`
+		PageViews      float64 `json:"pageViews"`
+	} `json:"sum"`
+	Uniq struct {
+		Uniques float64 `json:"uniques"`
+	} `json:"uniq"`
+	Dimensions map[string]string `json:"dimensions"`
+}
+
+// runExport implements "cf-metrics-collector export": it writes the
+// 1dGroups or 1hGroups totals of a date range as CSV or Parquet, one row
+// per zone and day or hour. Collector options follow after "--".
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("cf-metrics-collector export", flag.ExitOnError)
+	from := fs.String("from", time.Now().UTC().AddDate(0, 0, -7).Format("2006-01-02"), "First date, inclusive (YYYY-MM-DD, UTC)")
+	to := fs.String("to", time.Now().UTC().Format("2006-01-02"), "Last date, inclusive")
+	zoneList := fs.String("zones", "", "Comma-separated zone names or glob patterns, empty exports all zones")
+	granularity := fs.String("granularity", "day", "day (httpRequests1dGroups) or hour (httpRequests1hGroups)")
+	format := fs.String("format", "csv", "Output format: csv or parquet")
+	out := fs.String("out", "-", "Output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := loadConfig(fs.Args()); err != nil {
+		return err
+	}
+
+	g, ok := exportGranularities[*granularity]
+	if !ok {
+		return fmt.Errorf("unknown granularity %q", *granularity)
+	}
+	if *format != "csv" && *format != "parquet" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	start, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		return fmt.Errorf("bad -from: %w", err)
+	}
+	end, err := time.Parse("2006-01-02", *to)
+	if err != nil {
+		return fmt.Errorf("bad -to: %w", err)
+	}
+	end = end.AddDate(0, 0, 1)
+	if !end.After(start) {
+		return fmt.Errorf("-to is before -from")
+	}
+
+	if err := assignAllZones(); err != nil {
+		return err
+	}
+	selected := []Zone{}
+	for _, zone := range zones {
+		if *zoneList == "" || exportZoneMatches(zone, *zoneList) {
+			selected = append(selected, zone)
+		}
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no zone matches %q", *zoneList)
+	}
+
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if *out != "-" {
+		if f, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	columns := append([]string{"zone", g.dimension}, exportValueColumns...)
+	var ew exportWriter
+	if *format == "parquet" {
+		ew = newParquetExportWriter(w, columns)
+	} else {
+		ew = newCSVExportWriter(w, columns)
+	}
+
+	query := fmt.Sprintf(exportQuery, g.dataset, g.filterType, g.dimension)
+	rows := 0
+	for _, zone := range selected {
+		for chunkStart := start; chunkStart.Before(end); chunkStart = chunkStart.Add(g.chunk) {
+			chunkEnd := chunkStart.Add(g.chunk)
+			if chunkEnd.After(end) {
+				chunkEnd = end
+			}
+			z, err := queryZone[map[string][]exportRow](zone, query, map[string]any{"filter": zoneFilter(zone, g.dataset, map[string]any{
+				g.geq: chunkStart.Format(g.format),
+				g.lt:  chunkEnd.Format(g.format),
+			})})
+			if err != nil {
+				return fmt.Errorf("%s: %w", zone.Tag, err)
+			}
+			if z == nil {
+				continue
+			}
+			for _, r := range (*z)[g.dataset] {
+				err := ew.write(map[string]any{
+					"zone":            zone.Tag,
+					g.dimension:       r.Dimensions[g.dimension],
+					"requests":        int64(r.Sum.Requests),
+					"cached_requests": int64(r.Sum.CachedRequests),
+					"bytes":           int64(r.Sum.Bytes),
+					"cached_bytes":    int64(r.Sum.CachedBytes),
+					"threats":         int64(r.Sum.Threats),
+					"page_views":      int64(r.Sum.PageViews),
+					"uniques":         int64(r.Uniq.Uniques),
+				})
+				if err != nil {
+					return err
+				}
+				rows++
+			}
+		}
+	}
+	if err := ew.close(); err != nil {
+		return err
+	}
+	// a short write to a full disk only shows up on close
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	log.Println("[OK] Exported", rows, "rows of", len(selected), "zones")
+	return nil
+}
+
+// exportValueColumns follow the zone and date or datetime columns.
+var exportValueColumns = []string{"requests", "cached_requests", "bytes", "cached_bytes", "threats", "page_views", "uniques"}
+
+// exportWriter writes rows keyed by column name; the zone and time
+// columns are strings, the rest int64.
+type exportWriter interface {
+	write(row map[string]any) error
+	close() error
+}
+
+type csvExportWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func newCSVExportWriter(w io.Writer, columns []string) *csvExportWriter {
+	c := &csvExportWriter{w: csv.NewWriter(w), columns: columns}
+	// errors are kept by the csv.Writer and reported by close
+	c.w.Write(columns)
+	return c
+}
+
+func (c *csvExportWriter) write(row map[string]any) error {
+	record := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		switch v := row[col].(type) {
+		case string:
+			record[i] = v
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvExportWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// parquetExportWriter writes one Parquet file; columns are stored in
+// alphabetical order, readers pick them by name.
+type parquetExportWriter struct {
+	w *parquet.Writer
+}
+
+func newParquetExportWriter(w io.Writer, columns []string) *parquetExportWriter {
+	group := parquet.Group{}
+	for _, col := range columns[:2] {
+		group[col] = parquet.String()
+	}
+	for _, col := range columns[2:] {
+		group[col] = parquet.Int(64)
+	}
+	schema := parquet.NewSchema("cloudflare_export", group)
+	return &parquetExportWriter{w: parquet.NewWriter(w, schema, parquet.Compression(&parquet.Zstd))}
+}
+
+func (p *parquetExportWriter) write(row map[string]any) error {
+	return p.w.Write(row)
+}
+
+func (p *parquetExportWriter) close() error {
+	return p.w.Close()
+}
+
+func exportZoneMatches(zone Zone, patterns string) bool {
+	for _, p := range strings.Split(patterns, ",") {
+		if ok, _ := path.Match(strings.TrimSpace(p), zone.Tag); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	metricsDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cloudflare_exporter_metrics_duration_seconds",
+		Help:    "Time to serve /metrics by response code",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"code"})
+
+	metricsSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cloudflare_exporter_metrics_response_size_bytes",
+		Help:    "Size of /metrics responses by response code",
+		Buckets: prometheus.ExponentialBuckets(16<<10, 4, 8),
+	}, []string{"code"})
+
+	metricsSeries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudflare_exporter_metrics_series",
+		Help: "Series in the last /metrics response, before the exporter metrics of that response were updated",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(metricsDuration)
+	prometheus.MustRegister(metricsSize)
+	prometheus.MustRegister(metricsSeries)
+}
+
+// seriesCountingGatherer records the number of series it returns.
+type seriesCountingGatherer struct {
+	prometheus.Gatherer
+}
+
+func (g seriesCountingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	series := 0
+	for _, mf := range families {
+		series += len(mf.GetMetric())
+	}
+	metricsSeries.Set(float64(series))
+	return families, err
+}
+
+// instrumentMetricsHandler adds duration and size histograms to a
+// /metrics handler.
+func instrumentMetricsHandler(h http.Handler) http.Handler {
+	return promhttp.InstrumentHandlerDuration(metricsDuration, promhttp.InstrumentHandlerResponseSize(metricsSize, h))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var firewallEventsMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cloudflare_zone_firewall_events",
+		Help: "Security events per zone by action and rule source over the adaptive window",
+	},
+	[]string{"zone_tag", "action", "source"},
+)
+
+func init() {
+	cycleRegistry.MustRegister(firewallEventsMetric)
+
+	registerCollector(collector{
+		name:     "firewall_events",
+		scope:    "Zone Analytics",
+		datasets: []string{"firewallEventsAdaptiveGroups"},
+		zone:     fetchFirewallEvents,
+	})
+}
+
+const firewallEventsQuery = `query ($zoneTag: string!, $filter: ZoneFirewallEventsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      firewallEventsAdaptiveGroups(filter: $filter, limit: 1000, orderBy: [count_DESC]) {
+        count
+        dimensions { action source }
+      }
+    }
+  }
+}`
+
+func fetchFirewallEvents(zone Zone) error {
+	z, err := queryZone[struct {
+		FirewallEventsAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				Action string `json:"action"`
+				Source string `json:"source"`
+			} `json:"dimensions"`
+		} `json:"firewallEventsAdaptiveGroups"`
+	}](zone, firewallEventsQuery, map[string]any{
+		"filter": zoneFilter(zone, "firewallEventsAdaptiveGroups", adaptiveFilter()),
+	})
+	if err != nil {
+		return err
+	}
+
+	firewallEventsMetric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag})
+	if z == nil {
+		return nil
+	}
+	for _, g := range z.FirewallEventsAdaptiveGroups {
+		firewallEventsMetric.WithLabelValues(zone.Tag, g.Dimensions.Action, g.Dimensions.Source).Add(g.Count)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// adaptiveWindow is the time range covered by the adaptive dataset
+// collectors, ending at the moment of the query.
+var adaptiveWindow = time.Hour
+
+func adaptiveFilter() map[string]any {
+	now := time.Now().UTC()
+	return map[string]any{
+		"datetime_geq": now.Add(-adaptiveWindow).Format(time.RFC3339),
+		"datetime_lt":  now.Format(time.RFC3339),
+	}
+}
+
+// lookbackDays is the number of days, including today, reported by the
+// daily traffic metrics; each day is a separate series with a date label.
+var lookbackDays = 1
+
+// dailyChunkDays is the number of days requested by a single 1dGroups
+// query. Longer lookbacks are split so that no query runs into the
+// dataset's node limit and gets silently truncated.
+const dailyChunkDays = 30
+
+// dateChunk is an inclusive range of dates formatted as 2006-01-02.
+type dateChunk struct {
+	From, To string
+	Days     int
+}
+
+// lookbackChunks splits the given number of dates ending today (UTC) into
+// chunks of at most dailyChunkDays, newest first.
+func lookbackChunks(days int) []dateChunk {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	chunks := []dateChunk{}
+	for offset := 0; offset < days; offset += dailyChunkDays {
+		n := min(dailyChunkDays, days-offset)
+		to := today.AddDate(0, 0, -offset)
+		from := to.AddDate(0, 0, -(n - 1))
+		chunks = append(chunks, dateChunk{from.Format("2006-01-02"), to.Format("2006-01-02"), n})
+	}
+	return chunks
+}
+
+// zoneFilter combines a collector's filter for dataset with the extra
+// filters configured for the zone.
+func zoneFilter(zone Zone, dataset string, filter map[string]any) map[string]any {
+	and := []any{filter}
+	for _, zc := range zoneConfigs(zone) {
+		if extra, ok := zc.Filters[dataset]; ok {
+			and = append(and, extra)
+		}
+	}
+	if len(and) == 1 {
+		return filter
+	}
+	return map[string]any{"AND": and}
+}
+
+var apiErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cloudflare_api_errors_total",
+		Help: "Failed GraphQL queries per zone (account:<name> for account queries) by reason: graphql_error, empty_result, request_failed",
+	},
+	[]string{"zone_tag", "reason"},
+)
+
+func init() {
+	prometheus.MustRegister(apiErrors)
+}
+
+// countQueryError records a failed query of target. Errors are logged by
+// the collector, an empty result is logged here.
+func countQueryError(target string, err error) {
+	reason := "request_failed"
+	if err == nil {
+		reason = "empty_result"
+		log.Printf("[!] Cloudflare не вернул данных для %s (нет доступа к зоне или аккаунту?)", target)
+	} else if errors.As(err, new(graphqlErrors)) {
+		reason = "graphql_error"
+	}
+	apiErrors.WithLabelValues(target, reason).Inc()
+}
+
+// queryZone runs a viewer.zones query for a single zone and returns the
+// zone object, or nil when Cloudflare returned no zones. The query must
+// declare a $zoneTag variable.
+func queryZone[T any](zone Zone, query string, variables map[string]any) (*T, error) {
+	vars := map[string]any{"zoneTag": zone.ID}
+	for k, v := range variables {
+		vars[k] = v
+	}
+	var data struct {
+		Viewer struct {
+			Zones []T `json:"zones"`
+		} `json:"viewer"`
+	}
+	if err := cfGraphQL(query, vars, &data); err != nil {
+		countQueryError(zone.Tag, err)
+		return nil, err
+	}
+	if len(data.Viewer.Zones) == 0 {
+		countQueryError(zone.Tag, nil)
+		return nil, nil
+	}
+	return &data.Viewer.Zones[0], nil
+}
+
+// queryAccount is the viewer.accounts counterpart of queryZone. The query
+// must declare an $accountTag variable.
+func queryAccount[T any](account Account, query string, variables map[string]any) (*T, error) {
+	vars := map[string]any{"accountTag": account.ID}
+	for k, v := range variables {
+		vars[k] = v
+	}
+	var data struct {
+		Viewer struct {
+			Accounts []T `json:"accounts"`
+		} `json:"viewer"`
+	}
+	if err := cfGraphQL(query, vars, &data); err != nil {
+		countQueryError("account:"+account.Name, err)
+		return nil, err
+	}
+	if len(data.Viewer.Accounts) == 0 {
+		countQueryError("account:"+account.Name, nil)
+		return nil, nil
+	}
+	return &data.Viewer.Accounts[0], nil
+}
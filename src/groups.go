@@ -0,0 +1,147 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// groupMetrics are summed per zone group, exported as
+// cloudflare_zone_group_* with a zone_group label instead of zone_tag.
+var groupMetrics = "cloudflare_zone_requests_total,cloudflare_zone_cached_requests_total,cloudflare_zone_bandwidth_bytes_total,cloudflare_zone_cached_bandwidth_bytes_total,cloudflare_zone_threats_total,cloudflare_zone_status_code_requests_total"
+
+func init() {
+	cycleRegistry.MustRegister(zoneGroupCollector{})
+}
+
+// zoneGroups returns the groups of the zone from every matching zones entry.
+func zoneGroups(zone Zone) []string {
+	seen := map[string]bool{}
+	groups := []string{}
+	for _, zc := range zoneConfigs(zone) {
+		for _, g := range zc.Groups {
+			if !seen[g] {
+				seen[g] = true
+				groups = append(groups, g)
+			}
+		}
+	}
+	return groups
+}
+
+// zoneGroupCollector aggregates the selected zone series of the other
+// cycle vectors when they are collected, so the group sums always match
+// the zone series of the same snapshot. It is unchecked: the metric names
+// depend on groupMetrics.
+type zoneGroupCollector struct{}
+
+func (zoneGroupCollector) Describe(chan<- *prometheus.Desc) {}
+
+type groupSum struct {
+	name       string
+	help       string
+	valueType  prometheus.ValueType
+	labelNames []string
+	values     []string
+	sum        float64
+}
+
+func (zoneGroupCollector) Collect(ch chan<- prometheus.Metric) {
+	selected := map[string]bool{}
+	for _, name := range strings.Split(groupMetrics, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			selected[name] = true
+		}
+	}
+	if len(config.Zones) == 0 || len(selected) == 0 {
+		return
+	}
+
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		for _, c := range cycleRegistry.vecs {
+			if _, self := c.(zoneGroupCollector); !self {
+				c.Collect(metrics)
+			}
+		}
+		close(metrics)
+	}()
+
+	groupsByZone := map[string][]string{}
+	sums := map[string]*groupSum{}
+	for metric := range metrics {
+		m := descNamePattern.FindStringSubmatch(metric.Desc().String())
+		if m == nil || !selected[m[1]] {
+			continue
+		}
+		var pb dto.Metric
+		if metric.Write(&pb) != nil {
+			continue
+		}
+		var value float64
+		valueType := prometheus.GaugeValue
+		switch {
+		case pb.Gauge != nil:
+			value = pb.Gauge.GetValue()
+		case pb.Counter != nil:
+			value, valueType = pb.Counter.GetValue(), prometheus.CounterValue
+		default:
+			continue
+		}
+
+		zoneTag := ""
+		labelNames := []string{"zone_group"}
+		values := []string{""}
+		for _, lp := range pb.Label {
+			switch lp.GetName() {
+			case "zone_tag":
+				zoneTag = lp.GetValue()
+			case "account":
+				// added by zoneLabelsGatherer, groups may span accounts
+			default:
+				labelNames = append(labelNames, lp.GetName())
+				values = append(values, lp.GetValue())
+			}
+		}
+		groups, ok := groupsByZone[zoneTag]
+		if !ok {
+			groups = zoneGroups(Zone{Tag: zoneTag})
+			groupsByZone[zoneTag] = groups
+		}
+
+		for _, group := range groups {
+			values[0] = group
+			key := m[1] + "\xff" + strings.Join(values, "\xff")
+			s, ok := sums[key]
+			if !ok {
+				s = &groupSum{
+					name:       strings.Replace(m[1], "cloudflare_zone_", "cloudflare_zone_group_", 1),
+					help:       "Sum of " + m[1] + " over the zones of the group",
+					valueType:  valueType,
+					labelNames: labelNames,
+					values:     append([]string(nil), values...),
+				}
+				sums[key] = s
+			}
+			s.sum += value
+		}
+	}
+
+	keys := make([]string, 0, len(sums))
+	for key := range sums {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	descs := map[string]*prometheus.Desc{}
+	for _, key := range keys {
+		s := sums[key]
+		desc, ok := descs[s.name]
+		if !ok {
+			desc = prometheus.NewDesc(s.name, s.help, s.labelNames, nil)
+			descs[s.name] = desc
+		}
+		ch <- prometheus.MustNewConstMetric(desc, s.valueType, s.sum, s.values...)
+	}
+}
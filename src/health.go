@@ -0,0 +1,129 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Each component is scaled to 0 (fine) .. 1 (at or beyond its limit), the
+// score is 100 minus the weighted mean of the components in percent.
+const (
+	healthErrorRatioLimit  = 0.05
+	healthThreatRatioLimit = 0.2
+	healthLatencyLimitMs   = 2000.0
+)
+
+var (
+	healthErrorWeight   = 0.6
+	healthThreatWeight  = 0.2
+	healthLatencyWeight = 0.2
+
+	healthScore = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_health_score",
+			Help: "Composite zone health from 0 (bad) to 100 (healthy) over the adaptive window",
+		},
+		[]string{"zone_tag"},
+	)
+
+	healthComponent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_health_component",
+			Help: "Health score inputs scaled to 0 (fine) .. 1 (at its limit): errors, threats, latency",
+		},
+		[]string{"zone_tag", "component"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(healthScore)
+	cycleRegistry.MustRegister(healthComponent)
+
+	registerCollector(collector{
+		name:     "health",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups", "firewallEventsAdaptiveGroups"},
+		zone:     fetchHealth,
+	})
+}
+
+const healthQuery = `query ($zoneTag: string!, $all: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!, $errors: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!, $threats: ZoneFirewallEventsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      all: httpRequestsAdaptiveGroups(filter: $all, limit: 1) {
+        count
+        quantiles { originResponseDurationMsP95 }
+      }
+      errors: httpRequestsAdaptiveGroups(filter: $errors, limit: 1) {
+        count
+      }
+      threats: firewallEventsAdaptiveGroups(filter: $threats, limit: 1) {
+        count
+      }
+    }
+  }
+}`
+
+func fetchHealth(zone Zone) error {
+	errorsFilter := adaptiveFilter()
+	errorsFilter["edgeResponseStatus_geq"] = 500
+	errorsFilter["edgeResponseStatus_lt"] = 600
+	threatsFilter := adaptiveFilter()
+	threatsFilter["action_in"] = []string{"block", "challenge", "jschallenge", "managed_challenge"}
+
+	type countGroup struct {
+		Count float64 `json:"count"`
+	}
+	z, err := queryZone[struct {
+		All []struct {
+			Count     float64 `json:"count"`
+			Quantiles struct {
+				OriginP95 float64 `json:"originResponseDurationMsP95"`
+			} `json:"quantiles"`
+		} `json:"all"`
+		Errors  []countGroup `json:"errors"`
+		Threats []countGroup `json:"threats"`
+	}](zone, healthQuery, map[string]any{
+		"all":     zoneFilter(zone, "httpRequestsAdaptiveGroups", adaptiveFilter()),
+		"errors":  zoneFilter(zone, "httpRequestsAdaptiveGroups", errorsFilter),
+		"threats": zoneFilter(zone, "firewallEventsAdaptiveGroups", threatsFilter),
+	})
+	if err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	healthScore.DeletePartialMatch(byZone)
+	healthComponent.DeletePartialMatch(byZone)
+	if z == nil || len(z.All) == 0 || z.All[0].Count == 0 {
+		return nil
+	}
+
+	total := z.All[0].Count
+	first := func(groups []countGroup) float64 {
+		if len(groups) == 0 {
+			return 0
+		}
+		return groups[0].Count
+	}
+	components := map[string]float64{
+		"errors":  min(1, first(z.Errors)/total/healthErrorRatioLimit),
+		"threats": min(1, first(z.Threats)/total/healthThreatRatioLimit),
+		"latency": min(1, z.All[0].Quantiles.OriginP95/healthLatencyLimitMs),
+	}
+	weights := map[string]float64{
+		"errors":  healthErrorWeight,
+		"threats": healthThreatWeight,
+		"latency": healthLatencyWeight,
+	}
+
+	badness, weightSum := 0.0, 0.0
+	for name, v := range components {
+		healthComponent.WithLabelValues(zone.Tag, name).Set(v)
+		badness += weights[name] * v
+		weightSum += weights[name]
+	}
+	if weightSum > 0 {
+		healthScore.WithLabelValues(zone.Tag).Set(100 * (1 - badness/weightSum))
+	}
+	return nil
+}
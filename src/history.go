@@ -0,0 +1,214 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var (
+	historyPath      = ""
+	historyMetrics   = "cloudflare_zone_requests_total,cloudflare_zone_cached_requests_total,cloudflare_zone_page_views_total,cloudflare_zone_status_code_requests_total,cloudflare_zone_bandwidth_bytes_total"
+	historyRetention = time.Duration(0)
+
+	historyDB *sql.DB
+)
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS samples (
+	ts     INTEGER NOT NULL,
+	metric TEXT    NOT NULL,
+	zone   TEXT    NOT NULL,
+	labels TEXT    NOT NULL,
+	value  REAL    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS samples_metric_zone_ts ON samples (metric, zone, ts);
+`
+
+func openHistory() error {
+	if historyPath == "" {
+		return nil
+	}
+	db, err := sql.Open("sqlite", historyPath)
+	if err != nil {
+		return err
+	}
+	// one writer, the collection loop; sqlite does not like concurrent ones
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(historySchema + rollupSchema); err != nil {
+		db.Close()
+		return err
+	}
+	historyDB = db
+	log.Println("[OK] History store:", historyPath)
+	return nil
+}
+
+func historyMetricSet() map[string]bool {
+	set := map[string]bool{}
+	for _, name := range strings.Split(historyMetrics, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// recordHistory appends the current value of every selected series to the
+// store, called after each collection cycle.
+func recordHistory() {
+	if historyDB == nil {
+		return
+	}
+	families, err := cycleRegistry.Gather()
+	if err != nil {
+		log.Println("[!] Ошибка сбора метрик для истории:", err)
+		return
+	}
+
+	selected := historyMetricSet()
+	now := time.Now().Unix()
+	tx, err := historyDB.Begin()
+	if err != nil {
+		log.Println("[!] Ошибка записи истории:", err)
+		return
+	}
+	stmt, err := tx.Prepare("INSERT INTO samples (ts, metric, zone, labels, value) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		log.Println("[!] Ошибка записи истории:", err)
+		return
+	}
+	defer stmt.Close()
+
+	for _, family := range families {
+		if !selected[family.GetName()] {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			var value float64
+			switch {
+			case m.GetGauge() != nil:
+				value = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				value = m.GetCounter().GetValue()
+			default:
+				continue
+			}
+			zone := ""
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "zone_tag" {
+					zone = l.GetValue()
+				} else {
+					labels[l.GetName()] = l.GetValue()
+				}
+			}
+			encoded, _ := json.Marshal(labels)
+			if _, err := stmt.Exec(now, family.GetName(), zone, string(encoded), value); err != nil {
+				tx.Rollback()
+				log.Println("[!] Ошибка записи истории:", err)
+				return
+			}
+		}
+	}
+
+	if historyRetention > 0 {
+		cutoff := time.Now().Add(-historyRetention).Unix()
+		if _, err := tx.Exec("DELETE FROM samples WHERE ts < ?", cutoff); err != nil {
+			tx.Rollback()
+			log.Println("[!] Ошибка очистки истории:", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("[!] Ошибка записи истории:", err)
+	}
+}
+
+// parseHistoryTime accepts unix seconds or RFC 3339.
+func parseHistoryTime(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+type historySample struct {
+	Time   int64             `json:"ts"`
+	Zone   string            `json:"zone_tag,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// historyHandler serves /api/v1/history?metric=&zone=&from=&to=&limit=.
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if historyDB == nil {
+		http.Error(w, "history store is disabled", http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	metric := q.Get("metric")
+	if metric == "" {
+		http.Error(w, "metric is required", http.StatusBadRequest)
+		return
+	}
+	to, err := parseHistoryTime(q.Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, "bad to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := parseHistoryTime(q.Get("from"), to.Add(-7*24*time.Hour))
+	if err != nil {
+		http.Error(w, "bad from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := 10000
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 {
+		limit = min(v, 100000)
+	}
+
+	query := "SELECT ts, zone, labels, value FROM samples WHERE metric = ? AND ts >= ? AND ts <= ?"
+	args := []any{metric, from.Unix(), to.Unix()}
+	if zone := q.Get("zone"); zone != "" {
+		query += " AND zone = ?"
+		args = append(args, zone)
+	}
+	query += " ORDER BY ts LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := historyDB.Query(query, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	samples := []historySample{}
+	for rows.Next() {
+		var s historySample
+		var labels string
+		if err := rows.Scan(&s.Time, &s.Zone, &labels, &s.Value); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.Unmarshal([]byte(labels), &s.Labels)
+		samples = append(samples, s)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"metric": metric, "samples": samples})
+}
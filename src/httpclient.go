@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = ""
+
+var (
+	httpConnectTimeout = 10 * time.Second
+	// httpRequestTimeout limits a REST call including the body, GraphQL
+	// queries use GRAPHQL_TIMEOUT.
+	httpRequestTimeout = 30 * time.Second
+	// userAgent overrides the User-Agent of outgoing calls, by default it
+	// names the exporter, its version and the deployment.
+	userAgent = ""
+
+	cfClientOnce sync.Once
+	cfClient     *http.Client
+)
+
+// httpClient returns the client of the Cloudflare and DoH calls, built on first use
+// after the configuration is loaded. The proxy comes from HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY; every request carries USER_AGENT.
+func httpClient() *http.Client {
+	cfClientOnce.Do(func() {
+		dialer := &net.Dialer{Timeout: httpConnectTimeout, KeepAlive: 30 * time.Second}
+		ua := userAgent
+		if ua == "" {
+			ua = defaultUserAgent()
+		}
+		transport := &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   fetchConcurrency * 2,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   httpConnectTimeout,
+			ExpectContinueTimeout: time.Second,
+		}
+		cfClient = &http.Client{Transport: userAgentTransport{transport, ua}}
+	})
+	return cfClient
+}
+
+func exporterVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+				return s.Value[:12]
+			}
+		}
+	}
+	return "dev"
+}
+
+// defaultUserAgent identifies the deployment by REPLICA or the host name,
+// so API usage in Cloudflare audit logs can be attributed.
+func defaultUserAgent() string {
+	id := replicaLabel
+	if id == "" {
+		id, _ = os.Hostname()
+	}
+	ua := "cf-metrics-collector/" + exporterVersion()
+	if id != "" {
+		ua += " (instance " + id + ")"
+	}
+	return ua
+}
+
+// userAgentTransport sets the User-Agent on every request without one.
+type userAgentTransport struct {
+	http.RoundTripper
+	userAgent string
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	hyperdriveQueries = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_hyperdrive_queries",
+			Help: "Hyperdrive queries per configuration by cache status over the adaptive window",
+		},
+		[]string{"account", "config", "cache_status"},
+	)
+
+	hyperdriveCacheHitRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_hyperdrive_cache_hit_ratio",
+			Help: "Share of Hyperdrive queries served from cache over the adaptive window",
+		},
+		[]string{"account", "config"},
+	)
+
+	hyperdriveOriginConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_hyperdrive_origin_connections",
+			Help: "Peak number of origin database connections held by Hyperdrive over the adaptive window",
+		},
+		[]string{"account", "config"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(hyperdriveQueries)
+	cycleRegistry.MustRegister(hyperdriveCacheHitRatio)
+	cycleRegistry.MustRegister(hyperdriveOriginConnections)
+
+	registerCollector(collector{
+		name:     "hyperdrive",
+		scope:    "Hyperdrive",
+		datasets: []string{"hyperdriveQueriesAdaptiveGroups", "hyperdrivePoolAdaptiveGroups"},
+		account:  fetchHyperdrive,
+	})
+}
+
+const hyperdriveQuery = `query ($accountTag: string!, $filter: AccountHyperdriveQueriesAdaptiveGroupsFilter_InputObject!, $poolFilter: AccountHyperdrivePoolAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    accounts(filter: { accountTag: $accountTag }) {
+      hyperdriveQueriesAdaptiveGroups(filter: $filter, limit: 1000) {
+        count
+        dimensions { configId cacheStatus }
+      }
+      hyperdrivePoolAdaptiveGroups(filter: $poolFilter, limit: 1000) {
+        max { currentPoolSize }
+        dimensions { configId }
+      }
+    }
+  }
+}`
+
+func fetchHyperdrive(account Account) error {
+	var configs []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if _, err := cfGet("/accounts/"+account.ID+"/hyperdrive/configs", &configs); err != nil {
+		return err
+	}
+	names := map[string]string{}
+	for _, c := range configs {
+		names[c.ID] = c.Name
+	}
+	configName := func(id string) string {
+		if name := names[id]; name != "" {
+			return name
+		}
+		return id
+	}
+
+	a, err := queryAccount[struct {
+		HyperdriveQueriesAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				ConfigID    string `json:"configId"`
+				CacheStatus string `json:"cacheStatus"`
+			} `json:"dimensions"`
+		} `json:"hyperdriveQueriesAdaptiveGroups"`
+		HyperdrivePoolAdaptiveGroups []struct {
+			Max struct {
+				CurrentPoolSize float64 `json:"currentPoolSize"`
+			} `json:"max"`
+			Dimensions struct {
+				ConfigID string `json:"configId"`
+			} `json:"dimensions"`
+		} `json:"hyperdrivePoolAdaptiveGroups"`
+	}](account, hyperdriveQuery, map[string]any{"filter": adaptiveFilter(), "poolFilter": adaptiveFilter()})
+	if err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	hyperdriveQueries.DeletePartialMatch(byAccount)
+	hyperdriveCacheHitRatio.DeletePartialMatch(byAccount)
+	hyperdriveOriginConnections.DeletePartialMatch(byAccount)
+	if a == nil {
+		return nil
+	}
+
+	total := map[string]float64{}
+	hits := map[string]float64{}
+	for _, g := range a.HyperdriveQueriesAdaptiveGroups {
+		config := configName(g.Dimensions.ConfigID)
+		hyperdriveQueries.WithLabelValues(account.Name, config, g.Dimensions.CacheStatus).Add(g.Count)
+		total[config] += g.Count
+		if g.Dimensions.CacheStatus == "hit" {
+			hits[config] += g.Count
+		}
+	}
+	for config, n := range total {
+		if n > 0 {
+			hyperdriveCacheHitRatio.WithLabelValues(account.Name, config).Set(hits[config] / n)
+		}
+	}
+	for _, g := range a.HyperdrivePoolAdaptiveGroups {
+		hyperdriveOriginConnections.WithLabelValues(account.Name, configName(g.Dimensions.ConfigID)).Set(g.Max.CurrentPoolSize)
+	}
+	return nil
+}
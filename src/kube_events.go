@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serviceAccountDir holds the in-cluster credentials of the Pod.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+var (
+	// kubernetesEvents posts zone, collector and token events on the
+	// exporter's Pod, shown by kubectl describe pod.
+	kubernetesEvents = false
+	// tokenExpiryWarning is how long before expiry a token event is posted.
+	tokenExpiryWarning = 7 * 24 * time.Hour
+
+	kube *kubeEventSink
+
+	// at most kubeEventsPerMinute events are posted, so that an outage
+	// failing every zone does not flood the namespace
+	kubeEventsPerMinute = 30
+	kubeEventsMutex     = &sync.Mutex{}
+	kubeEventsWindow    time.Time
+	kubeEventsSent      int
+)
+
+type kubeEventSink struct {
+	client    *http.Client
+	apiURL    string
+	namespace string
+	pod       string
+}
+
+// openKubernetesEvents reads the in-cluster configuration. The Pod name
+// comes from POD_NAME (downward API), defaulting to the host name.
+func openKubernetesEvents() error {
+	if !kubernetesEvents {
+		return nil
+	}
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return fmt.Errorf("not running in a Kubernetes cluster")
+	}
+	namespace, err := os.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return err
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return fmt.Errorf("no certificates in %s/ca.crt", serviceAccountDir)
+	}
+	pod := os.Getenv("POD_NAME")
+	if pod == "" {
+		pod, _ = os.Hostname()
+	}
+	kube = &kubeEventSink{
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+		apiURL:    "https://" + net.JoinHostPort(host, port),
+		namespace: strings.TrimSpace(string(namespace)),
+		pod:       pod,
+	}
+	log.Println("[OK] Kubernetes events on pod", kube.namespace+"/"+kube.pod)
+	return nil
+}
+
+// kubeEvent posts an event in the background; eventType is Normal or
+// Warning, reason a CamelCase word.
+func kubeEvent(eventType, reason, message string) {
+	if kube == nil {
+		return
+	}
+	kubeEventsMutex.Lock()
+	if now := time.Now(); now.Sub(kubeEventsWindow) >= time.Minute {
+		kubeEventsWindow, kubeEventsSent = now, 0
+	}
+	kubeEventsSent++
+	dropped := kubeEventsSent > kubeEventsPerMinute
+	kubeEventsMutex.Unlock()
+	if dropped {
+		return
+	}
+	go func() {
+		if err := kube.post(eventType, reason, message); err != nil {
+			log.Println("[!] Ошибка отправки события Kubernetes:", err)
+		}
+	}()
+}
+
+func (k *kubeEventSink) post(eventType, reason, message string) error {
+	// bound service account tokens are rotated, read it every time
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	event := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"metadata":   map[string]any{"generateName": k.pod + ".", "namespace": k.namespace},
+		"involvedObject": map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"namespace":  k.namespace,
+			"name":       k.pod,
+		},
+		"type":               eventType,
+		"reason":             reason,
+		"message":            message,
+		"source":             map[string]any{"component": "cf-metrics-collector"},
+		"reportingComponent": "cf-metrics-collector",
+		"reportingInstance":  k.pod,
+		"firstTimestamp":     now,
+		"lastTimestamp":      now,
+		"count":              1,
+	}
+	body, _ := json.Marshal(event)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "POST", k.apiURL+"/api/v1/namespaces/"+k.namespace+"/events", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("POST events: status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// zoneLabels merges the labels of every zones entry matching the zone,
+// later patterns overriding earlier ones.
+func zoneLabels(zone Zone) map[string]string {
+	labels := map[string]string{}
+	for _, zc := range zoneConfigs(zone) {
+		for k, v := range zc.Labels {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// zoneAccountNames maps zone names to the name of their account.
+func zoneAccountNames() map[string]string {
+	zonesMutex.RLock()
+	defer zonesMutex.RUnlock()
+	names := map[string]string{}
+	for _, account := range accounts {
+		names[account.ID] = account.Name
+	}
+	byZone := map[string]string{}
+	for _, zone := range zones {
+		byZone[zone.Tag] = names[zone.AccountID]
+	}
+	return byZone
+}
+
+// zoneLabelsGatherer adds the configured zone labels (team, owner, ...) to
+// every series carrying a zone_tag label, so collectors don't need to know
+// about them. The account label is added whenever the zone's account is
+// known.
+type zoneLabelsGatherer struct {
+	prometheus.Gatherer
+}
+
+func (g zoneLabelsGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	accountNames := zoneAccountNames()
+	if len(config.Zones) == 0 && len(accountNames) == 0 {
+		return families, err
+	}
+
+	cache := map[string]map[string]string{}
+	for _, mf := range families {
+		for _, m := range mf.Metric {
+			zoneTag := ""
+			existing := map[string]bool{}
+			for _, lp := range m.Label {
+				existing[lp.GetName()] = true
+				if lp.GetName() == "zone_tag" {
+					zoneTag = lp.GetValue()
+				}
+			}
+			if zoneTag == "" {
+				continue
+			}
+			labels, ok := cache[zoneTag]
+			if !ok {
+				labels = zoneLabels(Zone{Tag: zoneTag})
+				if name := accountNames[zoneTag]; name != "" {
+					labels["account"] = name
+				}
+				cache[zoneTag] = labels
+			}
+			if len(labels) == 0 {
+				continue
+			}
+			for k, v := range labels {
+				if existing[k] || v == "" {
+					continue
+				}
+				m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(k), Value: proto.String(v)})
+			}
+			sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+		}
+	}
+	return families, err
+}
+
+var (
+	// replicaLabel and regionLabel are added to every exposed series when
+	// set, for Thanos or Mimir deduplication of exporters running at
+	// several sites.
+	replicaLabel = ""
+	regionLabel  = ""
+)
+
+// externalLabelsGatherer adds the replica and region labels.
+type externalLabelsGatherer struct {
+	prometheus.Gatherer
+}
+
+func (g externalLabelsGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	if replicaLabel == "" && regionLabel == "" {
+		return families, err
+	}
+	labels := map[string]string{"replica": replicaLabel, "region": regionLabel}
+	for _, mf := range families {
+		for _, m := range mf.Metric {
+			existing := map[string]bool{}
+			for _, lp := range m.Label {
+				existing[lp.GetName()] = true
+			}
+			for k, v := range labels {
+				if existing[k] || v == "" {
+					continue
+				}
+				m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(k), Value: proto.String(v)})
+			}
+			sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+		}
+	}
+	return families, err
+}
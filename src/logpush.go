@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// logpushMaxLine bounds a single NDJSON log line.
+	logpushMaxLine = 1 << 20
+	// logpushMaxBody bounds the request body as sent, before gunzip.
+	logpushMaxBody = 64 << 20
+)
+
+// Methods and cache statuses outside these sets are counted as __other__,
+// label values come from the payload.
+var (
+	logpushMethods = map[string]bool{
+		"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+		"DELETE": true, "OPTIONS": true, "CONNECT": true, "TRACE": true, "PURGE": true,
+	}
+	logpushCacheStatuses = map[string]bool{
+		"": true, "hit": true, "miss": true, "expired": true, "stale": true, "bypass": true,
+		"revalidated": true, "updating": true, "dynamic": true, "ignored": true, "deferred": true, "unknown": true, "none": true,
+	}
+)
+
+var (
+	logpushEnabled  = false
+	logpushPath     = "/logpush"
+	logpushAuth     = ""
+	logpushMaxPaths = 1000
+
+	// logpushPaths tracks the request paths counted per zone to bound the
+	// cardinality of the per-path counter
+	logpushPaths      = map[string]map[string]bool{}
+	logpushPathsMutex = &sync.Mutex{}
+
+	logpushBatches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_logpush_batches_total",
+			Help: "Logpush batches received by result",
+		},
+		[]string{"result"},
+	)
+
+	logpushLines = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_logpush_lines_total",
+			Help: "Logpush log lines received by result",
+		},
+		[]string{"result"},
+	)
+
+	logpushRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_logpush_requests_total",
+			Help: "HTTP requests seen in Logpush logs",
+		},
+		[]string{"zone_tag", "method", "status_class", "cache_status"},
+	)
+
+	logpushPathRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_logpush_path_requests_total",
+			Help: "HTTP requests seen in Logpush logs by path (bounded by LOGPUSH_MAX_PATHS per zone)",
+		},
+		[]string{"zone_tag", "path"},
+	)
+
+	logpushResponseBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_logpush_response_bytes_total",
+			Help: "Edge response bytes seen in Logpush logs",
+		},
+		[]string{"zone_tag"},
+	)
+
+	logpushEdgeTTFB = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cloudflare_logpush_edge_ttfb_seconds",
+			Help:    "Edge time to first byte from Logpush logs",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"zone_tag", "status_class"},
+	)
+
+	logpushOriginDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cloudflare_logpush_origin_duration_seconds",
+			Help:    "Origin response duration from Logpush logs (requests that reached the origin)",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 100},
+		},
+		[]string{"zone_tag", "status_class"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(logpushBatches)
+	prometheus.MustRegister(logpushLines)
+	prometheus.MustRegister(logpushRequests)
+	prometheus.MustRegister(logpushPathRequests)
+	prometheus.MustRegister(logpushResponseBytes)
+	prometheus.MustRegister(logpushEdgeTTFB)
+	prometheus.MustRegister(logpushOriginDuration)
+}
+
+// logpushRecord holds the http_requests dataset fields used for metrics.
+type logpushRecord struct {
+	RayID                    string  `json:"RayID"`
+	ZoneName                 string  `json:"ZoneName"`
+	ClientRequestHost        string  `json:"ClientRequestHost"`
+	ClientRequestMethod      string  `json:"ClientRequestMethod"`
+	ClientRequestPath        string  `json:"ClientRequestPath"`
+	ClientRequestURI         string  `json:"ClientRequestURI"`
+	EdgeResponseStatus       int     `json:"EdgeResponseStatus"`
+	EdgeResponseBytes        float64 `json:"EdgeResponseBytes"`
+	EdgeTimeToFirstByteMs    float64 `json:"EdgeTimeToFirstByteMs"`
+	OriginResponseStatus     int     `json:"OriginResponseStatus"`
+	OriginResponseDurationMs float64 `json:"OriginResponseDurationMs"`
+	CacheCacheStatus         string  `json:"CacheCacheStatus"`
+}
+
+// logpushHandler accepts Logpush HTTP destination batches: gzip compressed
+// newline delimited JSON.
+func logpushHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if logpushAuth != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(logpushAuth)) != 1 {
+		logpushBatches.WithLabelValues("unauthorized").Inc()
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, logpushMaxBody)
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			logpushBatches.WithLabelValues("invalid").Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), logpushMaxLine)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var rec logpushRecord
+		if err := json.Unmarshal(line, &rec); err != nil || rec.EdgeResponseStatus == 0 {
+			// Logpush validates destinations with a test payload that has
+			// no request fields
+			logpushLines.WithLabelValues("invalid").Inc()
+			continue
+		}
+		logpushLines.WithLabelValues("ok").Inc()
+		observeLogpushRecord(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("[!] Ошибка чтения Logpush батча:", err)
+		logpushBatches.WithLabelValues("invalid").Inc()
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logpushBatches.WithLabelValues("ok").Inc()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func observeLogpushRecord(rec logpushRecord) {
+	zone := rec.ZoneName
+	if zone == "" {
+		zone = rec.ClientRequestHost
+	}
+	// only discovered zones get their own series
+	if _, ok := findZone(zone); !ok {
+		zone = topOtherLabel
+	}
+	method := rec.ClientRequestMethod
+	if !logpushMethods[method] {
+		method = topOtherLabel
+	}
+	cacheStatus := rec.CacheCacheStatus
+	if !logpushCacheStatuses[cacheStatus] {
+		cacheStatus = topOtherLabel
+	}
+	class := statusClass(rec.EdgeResponseStatus)
+
+	// the Ray ID is the exemplar trace id, so a panel can jump from a spike
+	// to a request that can be looked up in Cloudflare
+	var exemplar prometheus.Labels
+	if isRayID(rec.RayID) {
+		exemplar = prometheus.Labels{"trace_id": rec.RayID}
+	}
+	addWithExemplar(logpushRequests.WithLabelValues(zone, method, class, cacheStatus), 1, exemplar)
+	addWithExemplar(logpushResponseBytes.WithLabelValues(zone), max(0, rec.EdgeResponseBytes), exemplar)
+	observeWithExemplar(logpushEdgeTTFB.WithLabelValues(zone, class), rec.EdgeTimeToFirstByteMs/1000, exemplar)
+	if rec.OriginResponseStatus != 0 {
+		observeWithExemplar(logpushOriginDuration.WithLabelValues(zone, statusClass(rec.OriginResponseStatus)), rec.OriginResponseDurationMs/1000, exemplar)
+	}
+
+	path := rec.ClientRequestPath
+	if path == "" {
+		path, _, _ = strings.Cut(rec.ClientRequestURI, "?")
+	}
+	logpushPathRequests.WithLabelValues(zone, logpushPathLabel(zone, path)).Inc()
+}
+
+// isRayID reports whether id looks like a Cloudflare Ray ID, 16 hex
+// digits. Anything else could push the exemplar past its 128 rune limit
+// or carry invalid UTF-8, and make the exemplar calls panic.
+func isRayID(id string) bool {
+	if len(id) != 16 {
+		return false
+	}
+	for _, c := range id {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func addWithExemplar(c prometheus.Counter, v float64, exemplar prometheus.Labels) {
+	if exemplar == nil {
+		c.Add(v)
+		return
+	}
+	c.(prometheus.ExemplarAdder).AddWithExemplar(v, exemplar)
+}
+
+func observeWithExemplar(o prometheus.Observer, v float64, exemplar prometheus.Labels) {
+	if exemplar == nil {
+		o.Observe(v)
+		return
+	}
+	o.(prometheus.ExemplarObserver).ObserveWithExemplar(v, exemplar)
+}
+
+func logpushPathLabel(zone, path string) string {
+	path = truncateLabel(path)
+
+	logpushPathsMutex.Lock()
+	defer logpushPathsMutex.Unlock()
+	seen := logpushPaths[zone]
+	if seen == nil {
+		seen = map[string]bool{}
+		logpushPaths[zone] = seen
+	}
+	if !seen[path] {
+		if len(seen) >= logpushMaxPaths {
+			return topOtherLabel
+		}
+		seen[path] = true
+	}
+	return path
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	magicFirewallPackets = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_magic_firewall_packets",
+			Help: "Packets matched by Magic Firewall rules over the adaptive window",
+		},
+		[]string{"account", "rule_id", "outcome"},
+	)
+
+	magicFirewallBits = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_magic_firewall_bits",
+			Help: "Bits matched by Magic Firewall rules over the adaptive window",
+		},
+		[]string{"account", "rule_id", "outcome"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(magicFirewallPackets)
+	cycleRegistry.MustRegister(magicFirewallBits)
+
+	registerCollector(collector{
+		name:     "magic_firewall",
+		scope:    "Account Analytics",
+		datasets: []string{"magicFirewallSamplesAdaptiveGroups"},
+		account:  fetchMagicFirewall,
+	})
+}
+
+const magicFirewallQuery = `query ($accountTag: string!, $filter: AccountMagicFirewallSamplesAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    accounts(filter: { accountTag: $accountTag }) {
+      magicFirewallSamplesAdaptiveGroups(filter: $filter, limit: 1000) {
+        sum { bits packets }
+        dimensions { ruleId outcome }
+      }
+    }
+  }
+}`
+
+func fetchMagicFirewall(account Account) error {
+	a, err := queryAccount[struct {
+		MagicFirewallSamplesAdaptiveGroups []struct {
+			Sum struct {
+				Bits    float64 `json:"bits"`
+				Packets float64 `json:"packets"`
+			} `json:"sum"`
+			Dimensions struct {
+				RuleID  string `json:"ruleId"`
+				Outcome string `json:"outcome"`
+			} `json:"dimensions"`
+		} `json:"magicFirewallSamplesAdaptiveGroups"`
+	}](account, magicFirewallQuery, map[string]any{"filter": adaptiveFilter()})
+	if err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	magicFirewallPackets.DeletePartialMatch(byAccount)
+	magicFirewallBits.DeletePartialMatch(byAccount)
+	if a == nil {
+		return nil
+	}
+	for _, g := range a.MagicFirewallSamplesAdaptiveGroups {
+		magicFirewallPackets.WithLabelValues(account.Name, g.Dimensions.RuleID, g.Dimensions.Outcome).Set(g.Sum.Packets)
+		magicFirewallBits.WithLabelValues(account.Name, g.Dimensions.RuleID, g.Dimensions.Outcome).Set(g.Sum.Bits)
+	}
+	return nil
+}
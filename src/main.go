@@ -2,14 +2,15 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
+	"path"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,54 +20,180 @@ import (
 )
 
 type Zone struct {
-	Tag string
-	ID  string
+	Tag       string
+	ID        string
+	AccountID string
+	// Token is the index in apiTokens() of the token that sees the zone.
+	Token int `json:",omitempty"`
+}
+
+type Account struct {
+	ID    string
+	Name  string
+	Token int `json:",omitempty"`
 }
 
 var (
 	apiToken   = ""
 	zones      = []Zone{}
+	accounts   = []Account{}
 	zonesMutex = &sync.RWMutex{}
 	cfBase     = "https://api.cloudflare.com/client/v4"
 
+	zonesInclude = ""
+	zonesExclude = ""
+	// zoneRefreshInterval re-lists the zones periodically, 0 only at start
+	zoneRefreshInterval = time.Hour
+
+	listenAddr = ":28191"
+	// trafficByCountry adds the per-country series, up to ~250 per zone
+	// and date
+	trafficByCountry = false
+
 	reqMetric = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "cloudflare_zone_requests_total",
-			Help: "Total requests per zone (GraphQL 1dGroups API)",
+			Help: "Total requests per zone and day (GraphQL 1dGroups API)",
 		},
-		[]string{"zone_tag"},
+		[]string{"zone_tag", "date"},
 	)
 
 	pageViews = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "cloudflare_zone_page_views_total",
-			Help: "Page views per zone (GraphQL 1dGroups API)",
+			Help: "Page views per zone and day (GraphQL 1dGroups API)",
+		},
+		[]string{"zone_tag", "date"},
+	)
+
+	uniquesMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_unique_visitors",
+			Help: "Unique visitors per zone and day (GraphQL 1dGroups API), not additive across days or zones",
 		},
-		[]string{"zone_tag"},
+		[]string{"zone_tag", "date"},
 	)
 
 	cachedMetric = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "cloudflare_zone_cached_requests_total",
-			Help: "Cached requests per zone (GraphQL 1dGroups API)",
+			Help: "Cached requests per zone and day (GraphQL 1dGroups API)",
+		},
+		[]string{"zone_tag", "date"},
+	)
+
+	threatsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_threats_total",
+			Help: "Blocked or challenged requests per zone and day by threat type (GraphQL 1dGroups API)",
+		},
+		[]string{"zone_tag", "date", "type"},
+	)
+
+	byCountryMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_requests_by_country_total",
+			Help: "Requests per zone and day by client country, with TRAFFIC_BY_COUNTRY (GraphQL 1dGroups API)",
+		},
+		[]string{"zone_tag", "date", "country"},
+	)
+
+	bandwidthMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_bandwidth_bytes_total",
+			Help: "Bytes served to clients per zone and day (GraphQL 1dGroups API)",
+		},
+		[]string{"zone_tag", "date"},
+	)
+
+	cachedBandwidthMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_cached_bandwidth_bytes_total",
+			Help: "Bytes served from cache per zone and day (GraphQL 1dGroups API)",
+		},
+		[]string{"zone_tag", "date"},
+	)
+
+	originRequestsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_origin_requests_total",
+			Help: "Requests per zone and day not served from cache, i.e. forwarded to the origin",
+		},
+		[]string{"zone_tag", "date"},
+	)
+
+	originBandwidthMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_origin_bandwidth_bytes_total",
+			Help: "Bytes per zone and day not served from cache",
+		},
+		[]string{"zone_tag", "date"},
+	)
+
+	originOffloadMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_origin_offload_ratio",
+			Help: "Share of requests per zone and day served from cache instead of the origin",
+		},
+		[]string{"zone_tag", "date"},
+	)
+
+	cacheHitRatioMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_cache_hit_ratio",
+			Help: "Cached requests divided by requests per zone and day, absent for days without requests",
+		},
+		[]string{"zone_tag", "date"},
+	)
+
+	cacheHitBytesRatioMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_cache_hit_bytes_ratio",
+			Help: "Cached bytes divided by bytes per zone and day, absent for days without traffic",
 		},
-		[]string{"zone_tag"},
+		[]string{"zone_tag", "date"},
 	)
 
 	byStatusMetric = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "cloudflare_zone_status_code_requests_total",
-			Help: "Requests per zone by HTTP status code",
+			Help: "Requests per zone and day by HTTP status code",
 		},
-		[]string{"zone_tag", "status_code"},
+		[]string{"zone_tag", "date", "status_code"},
+	)
+
+	statusClassMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_status_class_requests_total",
+			Help: "Requests per zone and day by HTTP status class (2xx, 3xx, 4xx, 5xx)",
+		},
+		[]string{"zone_tag", "date", "class"},
 	)
 )
 
 func init() {
-	prometheus.MustRegister(reqMetric)
-	prometheus.MustRegister(pageViews)
-	prometheus.MustRegister(cachedMetric)
-	prometheus.MustRegister(byStatusMetric)
+	cycleRegistry.MustRegister(reqMetric)
+	cycleRegistry.MustRegister(pageViews)
+	cycleRegistry.MustRegister(uniquesMetric)
+	cycleRegistry.MustRegister(cachedMetric)
+	cycleRegistry.MustRegister(byStatusMetric)
+	cycleRegistry.MustRegister(statusClassMetric)
+	cycleRegistry.MustRegister(originRequestsMetric)
+	cycleRegistry.MustRegister(originBandwidthMetric)
+	cycleRegistry.MustRegister(originOffloadMetric)
+	cycleRegistry.MustRegister(threatsMetric)
+	cycleRegistry.MustRegister(byCountryMetric)
+	cycleRegistry.MustRegister(bandwidthMetric)
+	cycleRegistry.MustRegister(cachedBandwidthMetric)
+	cycleRegistry.MustRegister(cacheHitRatioMetric)
+	cycleRegistry.MustRegister(cacheHitBytesRatioMetric)
+
+	registerCollector(collector{
+		name:     "traffic",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequests1dGroups"},
+		zone:     fetchZoneStats,
+	})
 }
 
 // func getZoneID(zoneTag string) (string, error) {
@@ -92,127 +219,288 @@ func init() {
 // 	return data.Result[0].ID, nil
 // }
 
-func assignAllZones() error {
-	u := fmt.Sprintf("%s/zones?per_page=500", cfBase)
-	req, _ := http.NewRequest("GET", u, nil)
-	req.Header.Set("Authorization", "Bearer "+apiToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	var data struct {
-		Result []struct {
-			ID     string `json:"id"`
-			Name   string `json:"name"`
-			Status string `json:"status"`
-		} `json:"result"`
-		ResultInfo struct {
-			Page       int `json:"page"`
-			PerPage    int `json:"per_page"`
-			TotalPages int `json:"total_pages"`
-		} `json:"result_info"`
-		Success bool `json:"success"`
-	}
-	if err := json.Unmarshal(body, &data); err != nil || len(data.Result) == 0 {
-		return fmt.Errorf("failed to get all zones %s", err)
+// zoneSelected applies ZONES_INCLUDE and ZONES_EXCLUDE (comma-separated
+// names or glob patterns) to a discovered zone name.
+func zoneSelected(name string) bool {
+	matches := func(list string) bool {
+		for _, pattern := range strings.Split(list, ",") {
+			if ok, _ := path.Match(strings.TrimSpace(pattern), name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if strings.TrimSpace(zonesInclude) != "" && !matches(zonesInclude) {
+		return false
 	}
+	return !matches(zonesExclude)
+}
+
+func assignAllZones() error {
 	zonesCopy := []Zone{}
-	for _, zone := range data.Result {
-		if zone.Status == "active" {
-			zoneCopy := Zone{
-				Tag: zone.Name,
-				ID:  zone.ID,
+	accountsCopy := []Account{}
+	seenZones := map[string]bool{}
+	seenAccounts := map[string]bool{}
+	// a zone visible to several tokens is fetched with the first one
+	for i, token := range apiTokens() {
+		// every page; the API caps per_page at 50 for zones
+		result, err := cfGetAllToken[struct {
+			ID      string `json:"id"`
+			Name    string `json:"name"`
+			Status  string `json:"status"`
+			Account struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"account"`
+		}](token, "/zones", 50)
+		if err != nil {
+			return fmt.Errorf("failed to get all zones %s", err)
+		}
+		if len(result) == 0 {
+			return fmt.Errorf("failed to get all zones: empty result")
+		}
+		for _, zone := range result {
+			if zone.Status == "active" && zoneSelected(zone.Name) && !seenZones[zone.ID] {
+				seenZones[zone.ID] = true
+				zoneCopy := Zone{
+					Tag:       zone.Name,
+					ID:        zone.ID,
+					AccountID: zone.Account.ID,
+					Token:     i,
+				}
+				zonesCopy = append(zonesCopy, zoneCopy)
+				if !seenAccounts[zone.Account.ID] {
+					seenAccounts[zone.Account.ID] = true
+					accountsCopy = append(accountsCopy, Account{ID: zone.Account.ID, Name: zone.Account.Name, Token: i})
+				}
 			}
-			zonesCopy = append(zonesCopy, zoneCopy)
 		}
 	}
+	zonesCopy, accountsCopy = applyZoneState(zonesCopy, accountsCopy)
 	if len(zonesCopy) == 0 {
 		return fmt.Errorf("no active zones found")
 	}
-	log.Println("[OK] Found zones:", len(zonesCopy))
+	log.Println("[OK] Found zones:", len(zonesCopy), "accounts:", len(accountsCopy))
 
 	zonesMutex.Lock()
 	zones = zonesCopy
+	accounts = accountsCopy
+	indexTokens()
 	zonesMutex.Unlock()
 
 	return nil
 }
 
-func fetchZoneStats(zone Zone) {
+// refreshZones re-lists the zones and drops the series and fetch status of
+// zones and accounts that are gone.
+func refreshZones() {
+	zonesMutex.RLock()
+	oldZones := append([]Zone{}, zones...)
+	oldAccounts := append([]Account{}, accounts...)
+	zonesMutex.RUnlock()
+
+	if err := assignAllZones(); err != nil {
+		log.Println("[!] Ошибка обновления списка зон:", err)
+		return
+	}
+	if redisClient != nil {
+		publishZones()
+	}
+
+	zonesMutex.RLock()
+	current := map[string]bool{}
+	for _, zone := range zones {
+		current[zone.Tag] = true
+	}
+	for _, account := range accounts {
+		current["account:"+account.Name] = true
+	}
+	zonesMutex.RUnlock()
+
+	known := map[string]bool{}
+	for _, zone := range oldZones {
+		known[zone.Tag] = true
+		if !current[zone.Tag] {
+			forgetTarget(zone.Tag, prometheus.Labels{"zone_tag": zone.Tag})
+			kubeEvent("Normal", "ZoneRemoved", "Zone "+zone.Tag+" is no longer accessible and was removed")
+		}
+	}
+	for tag := range current {
+		if !known[tag] && !strings.HasPrefix(tag, "account:") {
+			kubeEvent("Normal", "ZoneAdded", "Zone "+tag+" discovered")
+		}
+	}
+	for _, account := range oldAccounts {
+		if !current["account:"+account.Name] {
+			forgetTarget("account:"+account.Name, prometheus.Labels{"account": account.Name})
+		}
+	}
+}
+
+// forgetTarget drops the series and fetch statuses of a zone or account
+// that is no longer discovered.
+func forgetTarget(target string, labels prometheus.Labels) int {
+	n := cycleRegistry.deletePartialMatch(labels)
+	forgetTrafficDates(target)
+	forgetZoneDatasets(target)
+	forgetBurst(target)
+	forgetWorkersCron(target)
+	forgetDelegation(target)
+	fetchStatusesMutex.Lock()
+	for key := range fetchStatuses {
+		if key.target == target {
+			delete(fetchStatuses, key)
+		}
+	}
+	fetchStatusesMutex.Unlock()
+	log.Println("[OK] Removed", target, "series:", n)
+	return n
+}
+
+// zoneStatsQuery takes the optional sum fields, see trafficByCountry.
+const zoneStatsQuery = `query ($zoneTag: string!, $filter: ZoneHttpRequests1dGroupsFilter_InputObject!, $limit: uint64!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      httpRequests1dGroups(filter: $filter, limit: $limit, orderBy: [date_DESC]) {
+        sum {
+          requests cachedRequests pageViews bytes cachedBytes threats
+          responseStatusMap { edgeResponseStatus requests }
+          threatPathingMap { threatPathingName requests }
+          %s
+        }
+        uniq { uniques }
+        dimensions { date }
+      }
+    }
+  }
+}`
+
+type zoneDayGroup struct {
+	Sum struct {
+		Requests          float64 `json:"requests"`
+		CachedRequests    float64 `json:"cachedRequests"`
+		PageViews         float64 `json:"pageViews"`
+		Bytes             float64 `json:"bytes"`
+		CachedBytes       float64 `json:"cachedBytes"`
+		Threats           float64 `json:"threats"`
+		ResponseStatusMap []struct {
+			EdgeResponseStatus json.Number `json:"edgeResponseStatus"`
+			Requests           float64     `json:"requests"`
+		} `json:"responseStatusMap"`
+		ThreatPathingMap []struct {
+			ThreatPathingName string  `json:"threatPathingName"`
+			Requests          float64 `json:"requests"`
+		} `json:"threatPathingMap"`
+		CountryMap []struct {
+			ClientCountryName string  `json:"clientCountryName"`
+			Requests          float64 `json:"requests"`
+		} `json:"countryMap"`
+	} `json:"sum"`
+	Uniq struct {
+		Uniques float64 `json:"uniques"`
+	} `json:"uniq"`
+	Dimensions struct {
+		Date string `json:"date"`
+	} `json:"dimensions"`
+}
+
+func fetchZoneStats(zone Zone) error {
 	// zoneID, err := getZoneID(zoneTag)
 	// if err != nil {
 	// 	log.Printf("[!] Ошибка получения ID зоны %s: %v", zoneTag, err)
 	// 	return
 	// }
 	log.Println("[OK] Loading zoneTag:zoneID", zone.Tag, ":", zone.ID)
-	today := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
-	query := fmt.Sprintf(`{
-		"query": "query { viewer { zones(filter: { zoneTag: \"%s\" }) { httpRequests1dGroups( filter: { date_geq: \"%s\" }, limit: 1, orderBy: [date_DESC]) { sum { requests cachedRequests pageViews responseStatusMap { edgeResponseStatus requests } } dimensions { date } } } } }"
-	}`, zone.ID, today)
-
-	req, _ := http.NewRequest("POST", "https://api.cloudflare.com/client/v4/graphql", bytes.NewBuffer([]byte(query)))
-	req.Header.Set("Authorization", "Bearer "+apiToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Printf("[!] Ошибка Cloudflare GraphQL API для %s: %v", zone.Tag, err)
-		return
+
+	// final dates exported before are not queried again
+	dates := lookbackDates()
+	expireTrafficDates(zone, dates)
+	fetchDays := trafficFetchDays(zone, dates)
+
+	extra := ""
+	if trafficByCountry {
+		extra = "countryMap { clientCountryName requests }"
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	var result struct {
-		Data struct {
-			Viewer struct {
-				Zones []struct {
-					HttpRequests1dGroups []struct {
-						Sum struct {
-							Requests          float64 `json:"requests"`
-							CachedRequests    float64 `json:"cachedRequests"`
-							PageViews         float64 `json:"pageViews"`
-							ResponseStatusMap []struct {
-								EdgeResponseStatus json.Number `json:"edgeResponseStatus"`
-								Requests           float64     `json:"requests"`
-							} `json:"responseStatusMap"`
-						} `json:"sum"`
-						Dimensions struct {
-							Date string `json:"date"`
-						} `json:"dimensions"`
-					} `json:"httpRequests1dGroups"`
-				} `json:"zones"`
-			} `json:"viewer"`
-		} `json:"data"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		log.Printf("[!] Ошибка разбора GraphQL ответа для %s: %v", zone.Tag, err)
-		return
+	query := fmt.Sprintf(zoneStatsQuery, extra)
+
+	groups := []zoneDayGroup{}
+	for _, chunk := range lookbackChunks(fetchDays) {
+		filter := zoneFilter(zone, "httpRequests1dGroups", map[string]any{"date_geq": chunk.From, "date_leq": chunk.To})
+		z, err := queryZone[struct {
+			HttpRequests1dGroups []zoneDayGroup `json:"httpRequests1dGroups"`
+		}](zone, query, map[string]any{"filter": filter, "limit": chunk.Days})
+		if err != nil {
+			return err
+		}
+		if z != nil {
+			groups = append(groups, z.HttpRequests1dGroups...)
+		}
 	}
 
-	if len(result.Data.Viewer.Zones) == 0 || len(result.Data.Viewer.Zones[0].HttpRequests1dGroups) == 0 {
-		log.Printf("[!] Ошибка: нет данных для зоны %s", zone.Tag)
-		log.Println("[!] Ответ:", string(body))
-		log.Println("[!] Запрос:", query)
-		return
+	if len(groups) == 0 && fetchDays == len(dates) {
+		return fmt.Errorf("нет данных для зоны %s", zone.Tag)
 	}
 
-	for _, group := range result.Data.Viewer.Zones[0].HttpRequests1dGroups {
-		reqMetric.WithLabelValues(zone.Tag).Set(group.Sum.Requests)
-		pageViews.WithLabelValues(zone.Tag).Set(group.Sum.PageViews)
-		cachedMetric.WithLabelValues(zone.Tag).Set(group.Sum.CachedRequests)
+	latest := ""
+	for _, group := range groups {
+		date := group.Dimensions.Date
+		reqMetric.WithLabelValues(zone.Tag, date).Set(group.Sum.Requests)
+		pageViews.WithLabelValues(zone.Tag, date).Set(group.Sum.PageViews)
+		uniquesMetric.WithLabelValues(zone.Tag, date).Set(group.Uniq.Uniques)
+		cachedMetric.WithLabelValues(zone.Tag, date).Set(group.Sum.CachedRequests)
+		bandwidthMetric.WithLabelValues(zone.Tag, date).Set(group.Sum.Bytes)
+		cachedBandwidthMetric.WithLabelValues(zone.Tag, date).Set(group.Sum.CachedBytes)
+		originRequestsMetric.WithLabelValues(zone.Tag, date).Set(max(0, group.Sum.Requests-group.Sum.CachedRequests))
+		originBandwidthMetric.WithLabelValues(zone.Tag, date).Set(max(0, group.Sum.Bytes-group.Sum.CachedBytes))
+		if group.Sum.Requests > 0 {
+			originOffloadMetric.WithLabelValues(zone.Tag, date).Set(group.Sum.CachedRequests / group.Sum.Requests)
+			cacheHitRatioMetric.WithLabelValues(zone.Tag, date).Set(group.Sum.CachedRequests / group.Sum.Requests)
+		}
+		if group.Sum.Bytes > 0 {
+			cacheHitBytesRatioMetric.WithLabelValues(zone.Tag, date).Set(group.Sum.CachedBytes / group.Sum.Bytes)
+		}
+		byStatusMetric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag, "date": date})
+		statusClassMetric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag, "date": date})
+		// the classes alerts divide by are always present
+		for _, class := range []string{"2xx", "3xx", "4xx", "5xx"} {
+			statusClassMetric.WithLabelValues(zone.Tag, date, class).Set(0)
+		}
+		errors := 0.0
 		for _, status := range group.Sum.ResponseStatusMap {
-			EdgeResponseStatusStr := status.EdgeResponseStatus.String()
-			if EdgeResponseStatusStr != "" {
-				byStatusMetric.WithLabelValues(zone.Tag, EdgeResponseStatusStr).Set(status.Requests)
+			// several invalid codes may map to "unknown", so add up
+			code := statusCodeNumber(status.EdgeResponseStatus)
+			byStatusMetric.WithLabelValues(zone.Tag, date, code).Add(status.Requests)
+			class := "unknown"
+			if n, err := strconv.Atoi(code); err == nil {
+				class = statusClass(n)
+			}
+			statusClassMetric.WithLabelValues(zone.Tag, date, class).Add(status.Requests)
+			if code[0] == '5' {
+				errors += status.Requests
 			}
 		}
+		threatsMetric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag, "date": date})
+		typed := 0.0
+		for _, threat := range group.Sum.ThreatPathingMap {
+			threatsMetric.WithLabelValues(zone.Tag, date, threat.ThreatPathingName).Add(threat.Requests)
+			typed += threat.Requests
+		}
+		// keep the types adding up to the day's threats
+		if rest := group.Sum.Threats - typed; rest > 0 {
+			threatsMetric.WithLabelValues(zone.Tag, date, "unknown").Add(rest)
+		}
+		byCountryMetric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag, "date": date})
+		for _, country := range group.Sum.CountryMap {
+			byCountryMetric.WithLabelValues(zone.Tag, date, country.ClientCountryName).Add(country.Requests)
+		}
+		markTrafficDate(zone, date)
+		// the SLO gauges follow the newest date
+		if latest == "" || date > latest {
+			latest = date
+			updateSLO(zone, group.Sum.Requests, errors)
+		}
 	}
+	return nil
 }
 
 func main() {
@@ -226,27 +514,168 @@ func main() {
 		log.Println("Cant load .env: ", err)
 	}
 
-	apiToken = os.Getenv("CLOUDFLARE_API_TOKEN")
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:]); err != nil {
+			log.Println("[!] Ошибка экспорта:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := loadConfig(os.Args[1:]); err != nil {
+		log.Println("[!] Ошибка загрузки конфига:", err)
+		return
+	}
+	if err := checkBlackouts(); err != nil {
+		log.Println("[!] Ошибка в blackouts:", err)
+		return
+	}
+	setEnabledCollectors(collectorsList)
+	setUserAgentRules(userAgentGroups)
+	if err := openResponseCache(); err != nil {
+		log.Println("[!] Ошибка открытия кеша ответов:", err)
+		return
+	}
+	if err := openHistory(); err != nil {
+		log.Println("[!] Ошибка открытия хранилища истории:", err)
+		return
+	}
+
+	if err := loadState(); err != nil {
+		log.Println("[!] Ошибка чтения файла состояния:", err)
+		return
+	}
+
+	if err := openSharedCache(); err != nil {
+		log.Println("[!] Ошибка подключения к Redis:", err)
+		return
+	}
 
-	err := assignAllZones()
-	if err != nil {
-		log.Println("[!] Ошибка получения всех зон:", err)
+	if err := checkArchive(); err != nil {
+		log.Println("[!] Ошибка настройки архива циклов:", err)
+		return
+	}
+	if err := openKubernetesEvents(); err != nil {
+		log.Println("[!] Ошибка настройки событий Kubernetes:", err)
+		return
+	}
+	if compatMetrics != "" && compatMetrics != "lablabs" {
+		log.Println("[!] Неизвестный COMPAT_METRICS:", compatMetrics)
 		return
 	}
+	if logpushEnabled && logpushAuth == "" {
+		// the receiver shares the /metrics listener
+		log.Println("[!] LOGPUSH_ENABLED требует LOGPUSH_AUTHORIZATION")
+		return
+	}
+	if collectionMode != "loop" && !pullMode() {
+		log.Println("[!] Неизвестный COLLECTION_MODE:", collectionMode)
+		return
+	}
+	// the warm-up fills the snapshot, which scrape mode does not serve
+	if pullMode() {
+		usePullCollector()
+		log.Println("[OK] Collecting on scrape, cache TTL", scrapeCacheTTL)
+	} else if err := warmUp(); err != nil {
+		log.Println("[!] Ошибка прогрева из Prometheus:", err)
+	}
 
+	http.Handle("/metrics", instrumentMetricsHandler(promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(seriesCountingGatherer{externalLabelsGatherer{compatGatherer{zoneLabelsGatherer{sharedGatherer{exposedGatherer}}}}}, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)))
+	http.HandleFunc("/metrics/tenants/{tenant}", tenantMetricsHandler)
+	http.HandleFunc("/status", statusHandler)
+	http.HandleFunc("/readyz", readyzHandler)
+	http.HandleFunc("/api/v1/history", historyHandler)
+	http.HandleFunc("/api/v1/rollups", rollupsHandler)
+	if adminToken != "" {
+		registerAdminHandlers()
+		// both call the API live; /debug/token also shows the token policies
+		http.HandleFunc("/debug/token", adminAuth(tokenDebugHandler))
+		http.HandleFunc("/debug/preview", adminAuth(previewHandler))
+		log.Println("[OK] Admin API enabled on /admin/zones")
+	}
+	if logpushEnabled {
+		http.HandleFunc(logpushPath, logpushHandler)
+		log.Println("[OK] Принимаем Logpush на", logpushPath)
+	}
+
+	// serve right away, /readyz reports the startup progress
 	go func() {
-		for {
-			zonesMutex.RLock()
-			for _, zone := range zones {
-				fetchZoneStats(zone)
-			}
-			zonesMutex.RUnlock()
+		log.Println("[OK] Слушаем", listenAddr, "/metrics")
+		log.Fatal(http.ListenAndServe(listenAddr, nil))
+	}()
 
-			time.Sleep(5 * time.Minute)
-		}
+	// the token check and zone discovery don't depend on each other
+	var status tokenStatus
+	var tokenErr, zonesErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		status, tokenErr = verifyToken()
+	}()
+	go func() {
+		defer wg.Done()
+		zonesErr = discoverZones()
 	}()
+	wg.Wait()
 
-	http.Handle("/metrics", promhttp.Handler())
-	log.Println("[OK] Слушаем :28191 /metrics")
-	log.Fatal(http.ListenAndServe(":28191", nil))
+	if tokenErr != nil {
+		log.Println("[!] Ошибка проверки токена:", tokenErr)
+		return
+	}
+	if status.ExpiresOn.IsZero() {
+		log.Println("[OK] Token active, no expiry")
+	} else {
+		log.Println("[OK] Token active, expires:", status.ExpiresOn)
+	}
+	if zonesErr != nil {
+		log.Println("[!] Ошибка получения всех зон:", zonesErr)
+		return
+	}
+
+	if burstErrorRatio > 0 || burstThreatRatio > 0 {
+		go burstLoop()
+	}
+
+	if zoneRefreshInterval > 0 {
+		go func() {
+			for range time.Tick(zoneRefreshInterval) {
+				if isLeader() {
+					refreshZones()
+				}
+			}
+		}()
+	}
+
+	// the probe only reports, the first cycle doesn't wait for it
+	if verifyPermissions {
+		go checkCollectorPermissions()
+	}
+
+	for {
+		if !isLeader() {
+			if err := loadSharedZones(); err != nil {
+				log.Println("[!] Ошибка чтения списка зон из Redis:", err)
+			}
+			time.Sleep(redisLockTTL / 3)
+			continue
+		}
+		// in scrape mode /metrics runs the cycles
+		if pullMode() {
+			time.Sleep(scrapeInterval)
+			continue
+		}
+		// the last cycle's metrics stay exposed, also to the followers
+		if inBlackout() != "" {
+			publishMetrics()
+			time.Sleep(time.Minute)
+			continue
+		}
+		runCycle()
+		publishMetrics()
+		time.Sleep(scrapeInterval)
+	}
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var nelReportsMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cloudflare_zone_nel_reports",
+		Help: "Network Error Logging reports per zone by phase and type over the adaptive window",
+	},
+	[]string{"zone_tag", "phase", "type"},
+)
+
+func init() {
+	cycleRegistry.MustRegister(nelReportsMetric)
+
+	registerCollector(collector{
+		name:     "nel",
+		scope:    "Zone Analytics",
+		datasets: []string{"nelReportsAdaptiveGroups"},
+		zone:     fetchNELReports,
+	})
+}
+
+const nelQuery = `query ($zoneTag: string!, $filter: ZoneNelReportsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      nelReportsAdaptiveGroups(filter: $filter, limit: 1000) {
+        count
+        dimensions { phase type }
+      }
+    }
+  }
+}`
+
+func fetchNELReports(zone Zone) error {
+	z, err := queryZone[struct {
+		NelReportsAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				Phase string `json:"phase"`
+				Type  string `json:"type"`
+			} `json:"dimensions"`
+		} `json:"nelReportsAdaptiveGroups"`
+	}](zone, nelQuery, map[string]any{"filter": zoneFilter(zone, "nelReportsAdaptiveGroups", adaptiveFilter())})
+	if err != nil {
+		return err
+	}
+
+	nelReportsMetric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag})
+	if z == nil {
+		return nil
+	}
+	for _, g := range z.NelReportsAdaptiveGroups {
+		nelReportsMetric.WithLabelValues(zone.Tag, g.Dimensions.Phase, g.Dimensions.Type).Set(g.Count)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	notificationPolicyInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_notification_policy_info",
+			Help: "Configured Cloudflare Notification policies",
+		},
+		[]string{"account", "policy_id", "name", "alert_type", "enabled"},
+	)
+
+	notificationPolicyMechanisms = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_notification_policy_mechanisms",
+			Help: "Delivery mechanisms attached to a notification policy by type",
+		},
+		[]string{"account", "policy_id", "type"},
+	)
+
+	notificationPolicyDeliveryOK = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_notification_policy_delivery_ok",
+			Help: "1 if the policy has at least one mechanism and none of its webhooks is failing",
+		},
+		[]string{"account", "policy_id"},
+	)
+
+	notificationWebhookLastSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_notification_webhook_last_success_timestamp_seconds",
+			Help: "Last successful delivery to a notification webhook",
+		},
+		[]string{"account", "webhook_id", "name"},
+	)
+
+	notificationWebhookLastFailure = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_notification_webhook_last_failure_timestamp_seconds",
+			Help: "Last failed delivery to a notification webhook",
+		},
+		[]string{"account", "webhook_id", "name"},
+	)
+
+	notificationWebhookHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_notification_webhook_healthy",
+			Help: "1 if the last webhook delivery succeeded (or never failed)",
+		},
+		[]string{"account", "webhook_id", "name"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(notificationPolicyInfo)
+	cycleRegistry.MustRegister(notificationPolicyMechanisms)
+	cycleRegistry.MustRegister(notificationPolicyDeliveryOK)
+	cycleRegistry.MustRegister(notificationWebhookLastSuccess)
+	cycleRegistry.MustRegister(notificationWebhookLastFailure)
+	cycleRegistry.MustRegister(notificationWebhookHealthy)
+
+	registerCollector(collector{
+		name:    "notifications",
+		scope:   "Notifications",
+		account: fetchNotificationPolicies,
+	})
+}
+
+type notificationMechanism struct {
+	ID string `json:"id"`
+}
+
+func fetchNotificationPolicies(account Account) error {
+	var webhooks []struct {
+		ID          string     `json:"id"`
+		Name        string     `json:"name"`
+		LastSuccess *time.Time `json:"last_success"`
+		LastFailure *time.Time `json:"last_failure"`
+	}
+	if _, err := cfGet("/accounts/"+account.ID+"/alerting/v3/destinations/webhooks", &webhooks); err != nil {
+		return err
+	}
+
+	var policies []struct {
+		ID         string                             `json:"id"`
+		Name       string                             `json:"name"`
+		AlertType  string                             `json:"alert_type"`
+		Enabled    bool                               `json:"enabled"`
+		Mechanisms map[string][]notificationMechanism `json:"mechanisms"`
+	}
+	if _, err := cfGet("/accounts/"+account.ID+"/alerting/v3/policies", &policies); err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	notificationWebhookLastSuccess.DeletePartialMatch(byAccount)
+	notificationWebhookLastFailure.DeletePartialMatch(byAccount)
+	notificationWebhookHealthy.DeletePartialMatch(byAccount)
+	notificationPolicyInfo.DeletePartialMatch(byAccount)
+	notificationPolicyMechanisms.DeletePartialMatch(byAccount)
+	notificationPolicyDeliveryOK.DeletePartialMatch(byAccount)
+
+	healthy := map[string]bool{}
+	for _, w := range webhooks {
+		ok := w.LastFailure == nil || (w.LastSuccess != nil && w.LastSuccess.After(*w.LastFailure))
+		healthy[w.ID] = ok
+		if w.LastSuccess != nil {
+			notificationWebhookLastSuccess.WithLabelValues(account.Name, w.ID, w.Name).Set(float64(w.LastSuccess.Unix()))
+		}
+		if w.LastFailure != nil {
+			notificationWebhookLastFailure.WithLabelValues(account.Name, w.ID, w.Name).Set(float64(w.LastFailure.Unix()))
+		}
+		notificationWebhookHealthy.WithLabelValues(account.Name, w.ID, w.Name).Set(boolToFloat(ok))
+	}
+
+	for _, p := range policies {
+		notificationPolicyInfo.WithLabelValues(account.Name, p.ID, p.Name, p.AlertType, boolToString(p.Enabled)).Set(1)
+
+		total := 0
+		deliveryOK := true
+		for kind, mechanisms := range p.Mechanisms {
+			total += len(mechanisms)
+			notificationPolicyMechanisms.WithLabelValues(account.Name, p.ID, kind).Set(float64(len(mechanisms)))
+			if kind != "webhooks" {
+				continue
+			}
+			for _, m := range mechanisms {
+				if ok, known := healthy[m.ID]; known && !ok {
+					deliveryOK = false
+				}
+			}
+		}
+		notificationPolicyDeliveryOK.WithLabelValues(account.Name, p.ID).Set(boolToFloat(deliveryOK && total > 0))
+	}
+	return nil
+}
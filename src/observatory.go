@@ -0,0 +1,139 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	observatoryLabels = []string{"zone_tag", "url", "region", "device"}
+
+	observatoryScore = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_observatory_performance_score",
+			Help: "Lighthouse performance score of the last Observatory test",
+		},
+		observatoryLabels,
+	)
+
+	observatoryTTFB = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_observatory_ttfb_seconds",
+			Help: "Time to first byte of the last Observatory test",
+		},
+		observatoryLabels,
+	)
+
+	observatoryFCP = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_observatory_fcp_seconds",
+			Help: "First Contentful Paint of the last Observatory test",
+		},
+		observatoryLabels,
+	)
+
+	observatoryLCP = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_observatory_lcp_seconds",
+			Help: "Largest Contentful Paint of the last Observatory test",
+		},
+		observatoryLabels,
+	)
+
+	observatoryTBT = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_observatory_tbt_seconds",
+			Help: "Total Blocking Time of the last Observatory test",
+		},
+		observatoryLabels,
+	)
+
+	observatoryCLS = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_observatory_cls",
+			Help: "Cumulative Layout Shift of the last Observatory test",
+		},
+		observatoryLabels,
+	)
+
+	observatoryLastTest = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_observatory_last_test_timestamp_seconds",
+			Help: "Time of the last Observatory test of the page",
+		},
+		[]string{"zone_tag", "url", "region"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(observatoryScore)
+	cycleRegistry.MustRegister(observatoryTTFB)
+	cycleRegistry.MustRegister(observatoryFCP)
+	cycleRegistry.MustRegister(observatoryLCP)
+	cycleRegistry.MustRegister(observatoryTBT)
+	cycleRegistry.MustRegister(observatoryCLS)
+	cycleRegistry.MustRegister(observatoryLastTest)
+
+	registerCollector(collector{
+		name:  "observatory",
+		scope: "Zone Settings",
+		zone:  fetchObservatory,
+	})
+}
+
+// observatoryReport is a Lighthouse report, timings are in milliseconds.
+type observatoryReport struct {
+	State            string  `json:"state"`
+	PerformanceScore float64 `json:"performanceScore"`
+	TTFB             float64 `json:"ttfb"`
+	FCP              float64 `json:"fcp"`
+	LCP              float64 `json:"lcp"`
+	TBT              float64 `json:"tbt"`
+	CLS              float64 `json:"cls"`
+}
+
+func fetchObservatory(zone Zone) error {
+	var pages []struct {
+		URL    string `json:"url"`
+		Region struct {
+			Value string `json:"value"`
+		} `json:"region"`
+		LastTest struct {
+			Date          time.Time         `json:"date"`
+			DesktopReport observatoryReport `json:"desktopReport"`
+			MobileReport  observatoryReport `json:"mobileReport"`
+		} `json:"lastTest"`
+	}
+	if _, err := cfGet("/zones/"+zone.ID+"/speed_api/pages", &pages); err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	for _, m := range []*prometheus.GaugeVec{observatoryScore, observatoryTTFB, observatoryFCP, observatoryLCP, observatoryTBT, observatoryCLS, observatoryLastTest} {
+		m.DeletePartialMatch(byZone)
+	}
+
+	for _, page := range pages {
+		if page.LastTest.Date.IsZero() {
+			continue
+		}
+		observatoryLastTest.WithLabelValues(zone.Tag, page.URL, page.Region.Value).Set(float64(page.LastTest.Date.Unix()))
+		for device, report := range map[string]observatoryReport{
+			"desktop": page.LastTest.DesktopReport,
+			"mobile":  page.LastTest.MobileReport,
+		} {
+			if report.State != "COMPLETE" {
+				continue
+			}
+			labels := []string{zone.Tag, page.URL, page.Region.Value, device}
+			observatoryScore.WithLabelValues(labels...).Set(report.PerformanceScore)
+			observatoryTTFB.WithLabelValues(labels...).Set(report.TTFB / 1000)
+			observatoryFCP.WithLabelValues(labels...).Set(report.FCP / 1000)
+			observatoryLCP.WithLabelValues(labels...).Set(report.LCP / 1000)
+			observatoryTBT.WithLabelValues(labels...).Set(report.TBT / 1000)
+			observatoryCLS.WithLabelValues(labels...).Set(report.CLS)
+		}
+	}
+	return nil
+}
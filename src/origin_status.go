@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	originStatusMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_origin_status_code_requests",
+			Help: "Requests per zone by origin response status over the adaptive window (0: origin not contacted)",
+		},
+		[]string{"zone_tag", "status_code"},
+	)
+
+	edgeOriginStatusMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_edge_origin_status_requests",
+			Help: "Requests per zone by edge and origin response status over the adaptive window",
+		},
+		[]string{"zone_tag", "edge_status", "origin_status"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(originStatusMetric)
+	cycleRegistry.MustRegister(edgeOriginStatusMetric)
+
+	registerCollector(collector{
+		name:     "origin_status",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups"},
+		zone:     fetchOriginStatus,
+	})
+}
+
+const originStatusQuery = `query ($zoneTag: string!, $filter: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      httpRequestsAdaptiveGroups(filter: $filter, limit: 1000) {
+        count
+        avg { sampleInterval }
+        dimensions { edgeResponseStatus originResponseStatus }
+      }
+    }
+  }
+}`
+
+func fetchOriginStatus(zone Zone) error {
+	z, err := queryZone[struct {
+		HttpRequestsAdaptiveGroups []struct {
+			sampledGroup
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				EdgeResponseStatus   int `json:"edgeResponseStatus"`
+				OriginResponseStatus int `json:"originResponseStatus"`
+			} `json:"dimensions"`
+		} `json:"httpRequestsAdaptiveGroups"`
+	}](zone, originStatusQuery, map[string]any{"filter": zoneFilter(zone, "httpRequestsAdaptiveGroups", adaptiveFilter())})
+	if err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	originStatusMetric.DeletePartialMatch(byZone)
+	edgeOriginStatusMetric.DeletePartialMatch(byZone)
+	if z == nil {
+		return nil
+	}
+
+	byOrigin := map[string]float64{}
+	for _, g := range z.HttpRequestsAdaptiveGroups {
+		edge := statusCode(g.Dimensions.EdgeResponseStatus)
+		origin := originStatusCode(g.Dimensions.OriginResponseStatus)
+		count := sampledCount(g.Count, g.sampledGroup)
+		edgeOriginStatusMetric.WithLabelValues(zone.Tag, edge, origin).Add(count)
+		byOrigin[origin] += count
+	}
+	for status, count := range byOrigin {
+		originStatusMetric.WithLabelValues(zone.Tag, status).Set(count)
+	}
+	return nil
+}
+
+// originStatusCode is statusCode, keeping 0 for requests that never
+// reached the origin.
+func originStatusCode(code int) string {
+	if code == 0 {
+		return "0"
+	}
+	return statusCode(code)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	verifyPermissions = true
+
+	tokenValidMetric = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_token_valid",
+			Help: "1 if /user/tokens/verify reported the API token as active",
+		},
+	)
+
+	tokenPermissionMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_token_permission",
+			Help: "1 if the token could serve every enabled collector depending on the permission scope at startup",
+		},
+		[]string{"scope"},
+	)
+
+	collectorAvailableMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_collector_available",
+			Help: "1 if the enabled collector passed the startup permission and entitlement check",
+		},
+		[]string{"collector"},
+	)
+)
+
+// collectorCheck is the startup probe result of a collector.
+type collectorCheck struct {
+	Scope     string `json:"scope"`
+	Available bool   `json:"available"`
+	Error     string `json:"error,omitempty"`
+}
+
+var (
+	collectorChecks      = map[string]collectorCheck{}
+	collectorChecksMutex = &sync.RWMutex{}
+)
+
+// errNotEntitled marks GraphQL datasets that are disabled for the zone or
+// account plan.
+var errNotEntitled = errors.New("datasets not available")
+
+func init() {
+	prometheus.MustRegister(tokenValidMetric)
+	prometheus.MustRegister(tokenPermissionMetric)
+	prometheus.MustRegister(collectorAvailableMetric)
+}
+
+type tokenStatus struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	ExpiresOn time.Time `json:"expires_on"`
+	NotBefore time.Time `json:"not_before"`
+}
+
+// verifyToken checks every configured token and returns the status of
+// the first one that is not active, or of the main token.
+func verifyToken() (tokenStatus, error) {
+	var first tokenStatus
+	for i, token := range apiTokens() {
+		status, err := verifyAPIToken(token)
+		if err != nil {
+			tokenValidMetric.Set(0)
+			return status, err
+		}
+		if i == 0 {
+			first = status
+		}
+		if !status.ExpiresOn.IsZero() && time.Until(status.ExpiresOn) < tokenExpiryWarning {
+			kubeEvent("Warning", "TokenExpiring", fmt.Sprintf("API token %s expires %s", status.ID, status.ExpiresOn.Format(time.RFC3339)))
+		}
+	}
+	tokenValidMetric.Set(1)
+	return first, nil
+}
+
+func verifyAPIToken(token string) (tokenStatus, error) {
+	var status tokenStatus
+	if _, err := cfGetToken(token, "/user/tokens/verify", &status); err != nil {
+		return status, err
+	}
+	if status.Status != "active" {
+		return status, fmt.Errorf("token %s status %q", status.ID, status.Status)
+	}
+	return status, nil
+}
+
+// isPermissionError reports whether err means the token lacks access,
+// as opposed to a transient failure.
+func isPermissionError(err error) bool {
+	var reqErr *cfRequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.Status == http.StatusForbidden || reqErr.Status == http.StatusUnauthorized
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "not authorized") || strings.Contains(msg, "does not have access")
+}
+
+// datasetSettings checks the GraphQL settings node of the zone or account
+// and returns the datasets that are not enabled for it.
+func datasetSettings(datasets []string, zone *Zone, account *Account) ([]string, error) {
+	fields := ""
+	for _, d := range datasets {
+		fields += d + " { enabled } "
+	}
+
+	type settingsNode struct {
+		Settings map[string]*struct {
+			Enabled bool `json:"enabled"`
+		} `json:"settings"`
+	}
+	var node *settingsNode
+	var err error
+	if zone != nil {
+		query := `query ($zoneTag: string!) { viewer { zones(filter: { zoneTag: $zoneTag }) { settings { ` + fields + `} } } }`
+		node, err = queryZone[settingsNode](*zone, query, nil)
+	} else {
+		query := `query ($accountTag: string!) { viewer { accounts(filter: { accountTag: $accountTag }) { settings { ` + fields + `} } } }`
+		node, err = queryAccount[settingsNode](*account, query, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, d := range datasets {
+		if node == nil || node.Settings[d] == nil || !node.Settings[d].Enabled {
+			missing = append(missing, d)
+		}
+	}
+	return missing, nil
+}
+
+// scopeProbes are one lightweight GET per permission scope of the REST
+// collectors, {zone} and {account} are replaced by the probed IDs. Probing
+// instead of running the collectors keeps their state and series untouched
+// before the first cycle. GraphQL collectors are checked through
+// datasetSettings.
+var scopeProbes = map[string]string{
+	"Access: Audit Logs": "/accounts/{account}/audit_logs?per_page=1",
+	"Account Settings":   "/accounts/{account}/members?per_page=1",
+	"DNS":                "/zones/{zone}/dnssec",
+	"Firewall Services":  "/zones/{zone}/firewall/access_rules/rules?per_page=1",
+	"Notifications":      "/accounts/{account}/alerting/v3/policies",
+	"Page Rules":         "/zones/{zone}/pagerules",
+	"Workers Routes":     "/zones/{zone}/workers/routes",
+	"Zone":               "/zones?account.id={account}&per_page=1",
+	"Zone Settings":      "/zones/{zone}/settings/security_level",
+	"Zone WAF":           "/zones/{zone}/rulesets",
+}
+
+// checkCollectorPermissions probes every enabled collector once against the
+// first active zone or account and reports the ones the token cannot
+// serve.
+func checkCollectorPermissions() {
+	zonesMutex.RLock()
+	var zone *Zone
+	for _, z := range zones {
+		if !zonePaused(z) {
+			zone = &z
+			break
+		}
+	}
+	var account *Account
+	if len(accounts) > 0 {
+		a := accounts[0]
+		account = &a
+	}
+	zonesMutex.RUnlock()
+	if zone == nil || account == nil {
+		return
+	}
+
+	scopeOK := map[string]bool{}
+	probed := map[string]error{}
+	failed := []string{}
+	for _, c := range collectors {
+		if !enabledCollectors[c.name] {
+			continue
+		}
+		if _, seen := scopeOK[c.scope]; !seen {
+			scopeOK[c.scope] = true
+		}
+
+		var err error
+		if len(c.datasets) > 0 {
+			waitForBudget()
+			var missing []string
+			if c.zone != nil {
+				missing, err = datasetSettings(c.datasets, zone, nil)
+			} else {
+				missing, err = datasetSettings(c.datasets, nil, account)
+			}
+			if err == nil && len(missing) > 0 {
+				err = fmt.Errorf("%w: %s", errNotEntitled, strings.Join(missing, ", "))
+			}
+		} else if path, ok := scopeProbes[c.scope]; ok {
+			var done bool
+			if err, done = probed[c.scope]; !done {
+				waitForBudget()
+				path = strings.NewReplacer("{zone}", zone.ID, "{account}", account.ID).Replace(path)
+				_, err = cfGet(path, nil)
+				probed[c.scope] = err
+			}
+		}
+
+		available := err == nil || !isPermissionError(err) && !errors.Is(err, errNotEntitled)
+		collectorAvailableMetric.WithLabelValues(c.name).Set(boolToFloat(available))
+		check := collectorCheck{Scope: c.scope, Available: available}
+		if err != nil {
+			check.Error = err.Error()
+		}
+		collectorChecksMutex.Lock()
+		collectorChecks[c.name] = check
+		collectorChecksMutex.Unlock()
+		if !available {
+			scopeOK[c.scope] = false
+			failed = append(failed, c.name)
+			log.Printf("[!] Токен не может обслужить коллектор %s (%s): %v", c.name, c.scope, err)
+		} else if err != nil {
+			log.Printf("[!] Проверка коллектора %s: %v", c.name, err)
+		}
+	}
+	for scope, ok := range scopeOK {
+		tokenPermissionMetric.WithLabelValues(scope).Set(boolToFloat(ok))
+	}
+	if len(failed) > 0 {
+		log.Println("[!] Коллекторы без доступа:", strings.Join(failed, ", "))
+	} else {
+		log.Println("[OK] Token can serve all enabled collectors")
+	}
+}
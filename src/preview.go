@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// targetSeries returns the series of label=value from the collector
+// registry, keyed by metric family and encoded labels.
+func targetSeries(label, value string) (map[string]*dto.MetricFamily, map[string]string) {
+	families, _ := cycleRegistry.Gather()
+	byName := map[string]*dto.MetricFamily{}
+	series := map[string]string{}
+	for _, mf := range families {
+		for _, m := range mf.Metric {
+			match := false
+			pairs := make([]string, 0, len(m.Label))
+			for _, lp := range m.Label {
+				match = match || lp.GetName() == label && lp.GetValue() == value
+				pairs = append(pairs, lp.GetName()+"="+lp.GetValue())
+			}
+			if !match {
+				continue
+			}
+			byName[mf.GetName()] = mf
+			series[mf.GetName()+"{"+strings.Join(pairs, ",")+"}"] = m.String()
+		}
+	}
+	return byName, series
+}
+
+// previewHandler serves /debug/preview?collector=&zone= (or &account=): it
+// runs one collector for one target right away and prints the series it
+// produced in the text exposition format. The values reach /metrics only
+// with the next cycle snapshot.
+func previewHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var c *collector
+	for i := range collectors {
+		if collectors[i].name == q.Get("collector") {
+			c = &collectors[i]
+		}
+	}
+	if c == nil {
+		http.Error(w, "unknown collector", http.StatusBadRequest)
+		return
+	}
+
+	var label, value string
+	var run func() error
+	switch {
+	case q.Get("zone") != "" && c.zone != nil:
+		zone, ok := findZone(q.Get("zone"))
+		if !ok {
+			http.Error(w, "unknown zone", http.StatusNotFound)
+			return
+		}
+		label, value = "zone_tag", zone.Tag
+		run = func() error { return c.zone(zone) }
+	case q.Get("account") != "" && c.account != nil:
+		var account *Account
+		zonesMutex.RLock()
+		for _, a := range accounts {
+			if a.Name == q.Get("account") || a.ID == q.Get("account") {
+				account = &a
+			}
+		}
+		zonesMutex.RUnlock()
+		if account == nil {
+			http.Error(w, "unknown account", http.StatusNotFound)
+			return
+		}
+		label, value = "account", account.Name
+		run = func() error { return c.account(*account) }
+	default:
+		http.Error(w, "zone or account required, matching the collector type", http.StatusBadRequest)
+		return
+	}
+
+	// a run next to a cycle or burst pass would interleave with their
+	// delete-then-add updates and cursors such as the purge log position
+	if !collectMutex.TryLock() {
+		http.Error(w, "a collection cycle is running, retry later", http.StatusConflict)
+		return
+	}
+	var families map[string]*dto.MetricFamily
+	var before, after map[string]string
+	var err error
+	var duration time.Duration
+	func() {
+		defer collectMutex.Unlock()
+		_, before = targetSeries(label, value)
+		waitForBudget()
+		start := time.Now()
+		err = run()
+		duration = time.Since(start)
+		families, after = targetSeries(label, value)
+	}()
+
+	// a family belongs to the collector if any of the target's series
+	// changed, appeared or disappeared
+	changed := map[string]bool{}
+	for key, m := range after {
+		if before[key] != m {
+			changed[key[:strings.Index(key, "{")]] = true
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed[key[:strings.Index(key, "{")]] = true
+		}
+	}
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "# collector %s, %s=%s, took %s\n", c.name, label, value, duration.Round(time.Millisecond))
+	if err != nil {
+		fmt.Fprintf(w, "# error: %v\n", err)
+	}
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, name := range names {
+		mf, ok := families[name]
+		if !ok {
+			continue
+		}
+		metrics := mf.Metric[:0]
+		for _, m := range mf.Metric {
+			for _, lp := range m.Label {
+				if lp.GetName() == label && lp.GetValue() == value {
+					metrics = append(metrics, m)
+					break
+				}
+			}
+		}
+		mf.Metric = metrics
+		enc.Encode(mf)
+	}
+}
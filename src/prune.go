@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// knownTargets are the zones and accounts discovered in an earlier
+	// cycle. Only these are pruned once they are gone: series keyed by
+	// zones outside discovery on purpose (pending or excluded zones,
+	// audit log zone names) stay.
+	knownTargets = map[string]bool{}
+
+	prunedSeries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_exporter_pruned_series_total",
+			Help: "Series removed after a cycle because their zone or account is gone or their date left the lookback window",
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(prunedSeries)
+}
+
+// pruneStaleSeries runs after each cycle. Zone refreshes and the traffic
+// collector already drop what they replace, this catches the rest: series
+// of targets discovered before and now missing from the zone list, and
+// dates of zones that are paused or whose traffic fetch keeps failing.
+func pruneStaleSeries() {
+	zonesMutex.RLock()
+	if len(zones) == 0 {
+		zonesMutex.RUnlock()
+		return
+	}
+	current := map[string]bool{}
+	for _, zone := range zones {
+		current[zone.Tag] = true
+	}
+	for _, account := range accounts {
+		current["account:"+account.Name] = true
+	}
+	zonesMutex.RUnlock()
+	gone := map[string]bool{}
+	for target := range knownTargets {
+		if !current[target] {
+			gone[target] = true
+			delete(knownTargets, target)
+		}
+	}
+	for target := range current {
+		knownTargets[target] = true
+	}
+
+	families, err := cycleRegistry.Gather()
+	if err != nil {
+		log.Println("[!] Ошибка сбора метрик для очистки:", err)
+		return
+	}
+	dates := lookbackDates()
+	oldest := dates[len(dates)-1]
+
+	goneTargets := map[string]prometheus.Labels{}
+	oldDates := map[[2]string]bool{}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			var zoneTag, account, date string
+			for _, lp := range m.GetLabel() {
+				switch lp.GetName() {
+				case "zone_tag":
+					zoneTag = lp.GetValue()
+				case "account":
+					account = lp.GetValue()
+				case "date":
+					date = lp.GetValue()
+				}
+			}
+			switch {
+			case zoneTag != "" && gone[zoneTag]:
+				goneTargets[zoneTag] = prometheus.Labels{"zone_tag": zoneTag}
+			case zoneTag == "" && account != "" && gone["account:"+account]:
+				goneTargets["account:"+account] = prometheus.Labels{"account": account}
+			case zoneTag != "" && date != "" && date < oldest:
+				oldDates[[2]string{zoneTag, date}] = true
+			}
+		}
+	}
+
+	for target, labels := range goneTargets {
+		prunedSeries.WithLabelValues("gone").Add(float64(forgetTarget(target, labels)))
+	}
+	if len(oldDates) == 0 {
+		return
+	}
+	n := 0
+	trafficDatesMutex.Lock()
+	for key := range oldDates {
+		for _, m := range dailyTrafficMetrics() {
+			n += m.DeletePartialMatch(prometheus.Labels{"zone_tag": key[0], "date": key[1]})
+		}
+		delete(trafficDates[key[0]], key[1])
+	}
+	trafficDatesMutex.Unlock()
+	prunedSeries.WithLabelValues("expired_date").Add(float64(n))
+	log.Println("[OK] Pruned series of dates before", oldest+":", n)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// collectionMode is "loop" for the background collection cycle or
+	// "scrape" to query Cloudflare when /metrics is scraped.
+	collectionMode = "loop"
+	// scrapeCacheTTL is how long a scrape-triggered cycle is reused by
+	// the following scrapes.
+	scrapeCacheTTL = time.Minute
+
+	pullMutex = &sync.Mutex{}
+	pullLast  time.Time
+)
+
+func pullMode() bool {
+	return collectionMode == "scrape"
+}
+
+// pullCollector runs a collection cycle when its data is older than
+// scrapeCacheTTL and then exposes the collector vectors. Scrapes arriving
+// during a cycle wait for it and share its result.
+type pullCollector struct{}
+
+func (pullCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range cycleRegistry.vecs {
+		c.Describe(ch)
+	}
+}
+
+func (pullCollector) Collect(ch chan<- prometheus.Metric) {
+	pullMutex.Lock()
+	defer pullMutex.Unlock()
+
+	zonesMutex.RLock()
+	discovered := len(zones) > 0
+	zonesMutex.RUnlock()
+	// before zone discovery a cycle would only cache an empty result
+	if discovered && time.Since(pullLast) >= scrapeCacheTTL && inBlackout() == "" {
+		runCycle()
+		pullLast = time.Now()
+		publishMetrics()
+	}
+	for _, c := range cycleRegistry.vecs {
+		c.Collect(ch)
+	}
+}
+
+// usePullCollector switches /metrics to scrape-time collection.
+func usePullCollector() {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(pullCollector{})
+	exposedGatherer = prometheus.Gatherers{registry, prometheus.DefaultGatherer}
+}
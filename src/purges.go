@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cachePurgesMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_zone_cache_purges_total",
+			Help: "Cache purge API calls seen in the account audit log by purge type",
+		},
+		[]string{"zone_tag", "type"},
+	)
+
+	// purgesSince holds the timestamp of the newest audit entry already
+	// counted per account
+	purgesSince      = map[string]time.Time{}
+	purgesSinceMutex = &sync.Mutex{}
+)
+
+func init() {
+	cycleRegistry.MustRegister(cachePurgesMetric)
+
+	registerCollector(collector{
+		name:    "cache_purges",
+		scope:   "Access: Audit Logs",
+		account: fetchCachePurges,
+	})
+}
+
+func fetchCachePurges(account Account) error {
+	purgesSinceMutex.Lock()
+	since, ok := purgesSince[account.ID]
+	purgesSinceMutex.Unlock()
+	if !ok {
+		// start counting from the first cycle, history before the exporter
+		// started is not interesting for a counter
+		since = time.Now().UTC()
+	}
+
+	zoneTags := map[string]string{}
+	zonesMutex.RLock()
+	for _, zone := range zones {
+		if zone.AccountID == account.ID {
+			zoneTags[zone.ID] = zone.Tag
+		}
+	}
+	zonesMutex.RUnlock()
+
+	// counted only once every page is read, a failed page leaves since
+	// alone and the next cycle reads the same entries again
+	type purgeKey struct{ zoneTag, kind string }
+	counts := map[purgeKey]float64{}
+	newest := since
+	for page := 1; ; page++ {
+		q := url.Values{}
+		q.Set("action.type", "purge")
+		q.Set("since", since.Format(time.RFC3339))
+		q.Set("direction", "asc")
+		q.Set("per_page", "1000")
+		q.Set("page", strconv.Itoa(page))
+
+		var entries []struct {
+			When     time.Time `json:"when"`
+			Resource struct {
+				ID string `json:"id"`
+			} `json:"resource"`
+			Metadata struct {
+				ZoneName string `json:"zone_name"`
+			} `json:"metadata"`
+			NewValueJSON map[string]json.RawMessage `json:"newValueJson"`
+		}
+		info, err := cfGet("/accounts/"+account.ID+"/audit_logs?"+q.Encode(), &entries)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.When.After(since) {
+				continue
+			}
+			if e.When.After(newest) {
+				newest = e.When
+			}
+			zoneTag := e.Metadata.ZoneName
+			if tag, ok := zoneTags[e.Resource.ID]; ok {
+				zoneTag = tag
+			}
+			counts[purgeKey{zoneTag, purgeType(e.NewValueJSON)}]++
+		}
+		if len(entries) == 0 || page >= info.TotalPages {
+			break
+		}
+	}
+
+	for key, n := range counts {
+		cachePurgesMetric.WithLabelValues(key.zoneTag, key.kind).Add(n)
+	}
+
+	purgesSinceMutex.Lock()
+	purgesSince[account.ID] = newest
+	purgesSinceMutex.Unlock()
+	return nil
+}
+
+func purgeType(body map[string]json.RawMessage) string {
+	for _, kind := range []string{"files", "tags", "hosts", "prefixes"} {
+		if _, ok := body[kind]; ok {
+			return kind
+		}
+	}
+	if _, ok := body["purge_everything"]; ok {
+		return "everything"
+	}
+	return "unknown"
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// readinessTimeout is how long /readyz waits for every collector to succeed
+// once before reporting ready with partial data.
+var readinessTimeout = 10 * time.Minute
+
+var (
+	startedAt = time.Now()
+	ready     atomic.Bool
+
+	readyMetric = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cloudflare_exporter_ready",
+		Help: "1 once every enabled collector succeeded for every zone, or the readiness timeout passed",
+	}, func() float64 { return boolToFloat(isReady()) })
+)
+
+func init() {
+	prometheus.MustRegister(readyMetric)
+}
+
+// pendingFetches lists the collector/target pairs of the discovered zones
+// and accounts that never succeeded. Paused zones are not waited for.
+func pendingFetches() []string {
+	zonesMutex.RLock()
+	defer zonesMutex.RUnlock()
+	fetchStatusesMutex.RLock()
+	defer fetchStatusesMutex.RUnlock()
+
+	pending := []string{}
+	if len(zones) == 0 {
+		return []string{"zone discovery"}
+	}
+	for _, c := range collectors {
+		if !enabledCollectors[c.name] {
+			continue
+		}
+		if c.account != nil {
+			for _, account := range accounts {
+				if fetchStatuses[fetchKey{c.name, "account:" + account.Name}].LastSuccess.IsZero() {
+					pending = append(pending, c.name+"/account:"+account.Name)
+				}
+			}
+		}
+		if c.zone != nil {
+			for _, zone := range zones {
+				if !zonePaused(zone) && fetchStatuses[fetchKey{c.name, zone.Tag}].LastSuccess.IsZero() {
+					pending = append(pending, c.name+"/"+zone.Tag)
+				}
+			}
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// isReady latches once the first complete cycle succeeded or the timeout
+// passed. Followers are ready as soon as the leader published metrics.
+func isReady() bool {
+	if ready.Load() {
+		return true
+	}
+	if redisClient != nil && !isLeader() {
+		n, err := redisClient.Exists(context.Background(), redisKey("metrics")).Result()
+		return err == nil && n > 0
+	}
+	if len(pendingFetches()) == 0 || time.Since(startedAt) > readinessTimeout {
+		ready.Store(true)
+	}
+	return ready.Load()
+}
+
+// readyzHandler serves /readyz: 503 until ready, then 200 with the fetches
+// that still never succeeded, if any.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ok := isReady()
+	pending := []string{}
+	if redisClient == nil || isLeader() {
+		pending = pendingFetches()
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "not ready, %d fetches pending:\n%s\n", len(pending), strings.Join(pending, "\n"))
+		return
+	}
+	if len(pending) > 0 {
+		fmt.Fprintf(w, "ready (partial), %d fetches never succeeded:\n%s\n", len(pending), strings.Join(pending, "\n"))
+		return
+	}
+	fmt.Fprintln(w, "ready")
+}
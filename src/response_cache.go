@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	responseCachePath = ""
+	responseCacheTTL  = time.Hour
+
+	responseCacheDB     *bolt.DB
+	responseCacheBucket = []byte("graphql")
+
+	responseCacheServed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cloudflare_response_cache_served_total",
+			Help: "GraphQL responses served from the on-disk cache because Cloudflare was unreachable",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(responseCacheServed)
+}
+
+func openResponseCache() error {
+	if responseCachePath == "" {
+		return nil
+	}
+	db, err := bolt.Open(responseCachePath, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(responseCacheBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return err
+	}
+	responseCacheDB = db
+	log.Println("[OK] Response cache:", responseCachePath)
+	return nil
+}
+
+// responseCacheKey identifies a collector query for one zone or account:
+// the query text plus every variable except the rolling datetime_* bounds,
+// which change on every call. Chunk bounds (date_*) and filters are part
+// of the key, so chunks and filter variants of one query do not collide.
+func responseCacheKey(query string, variables map[string]any) []byte {
+	sum := sha256.Sum256([]byte(query))
+	vars, _ := json.Marshal(withoutRollingBounds(variables))
+	varsSum := sha256.Sum256(vars)
+	tag := variables["zoneTag"]
+	if tag == nil {
+		tag = variables["accountTag"]
+	}
+	return []byte(fmt.Sprintf("%s:%v:%s", hex.EncodeToString(sum[:8]), tag, hex.EncodeToString(varsSum[:8])))
+}
+
+// withoutRollingBounds copies v without datetime_* filter keys at any depth.
+func withoutRollingBounds(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			if !strings.HasPrefix(k, "datetime_") {
+				out[k] = withoutRollingBounds(item)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = withoutRollingBounds(item)
+		}
+		return out
+	}
+	return v
+}
+
+func responseCachePut(key, data []byte) {
+	if responseCacheDB == nil {
+		return
+	}
+	value := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(value, uint64(time.Now().Unix()))
+	value = append(value, data...)
+	err := responseCacheDB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(responseCacheBucket).Put(key, value)
+	})
+	if err != nil {
+		log.Println("[!] Ошибка записи в кеш ответов:", err)
+	}
+}
+
+// responseCacheGet returns the cached response if it is younger than the TTL.
+func responseCacheGet(key []byte) ([]byte, bool) {
+	if responseCacheDB == nil {
+		return nil, false
+	}
+	var data []byte
+	responseCacheDB.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(responseCacheBucket).Get(key)
+		if len(value) < 8 {
+			return nil
+		}
+		stored := time.Unix(int64(binary.BigEndian.Uint64(value[:8])), 0)
+		if time.Since(stored) > responseCacheTTL {
+			return nil
+		}
+		data = append([]byte{}, value[8:]...)
+		return nil
+	})
+	if data == nil {
+		return nil, false
+	}
+	responseCacheServed.Inc()
+	return data, true
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// maxRetries is the number of repeated attempts after a 429, a 5xx
+	// gateway error or a network failure; 0 disables retries.
+	maxRetries     = 3
+	retryBaseDelay = time.Second
+
+	retriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_exporter_api_retries_total",
+			Help: "Cloudflare API requests repeated after a rate limit or transient failure",
+		},
+		[]string{"api"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(retriesTotal)
+}
+
+// retryable reports whether the attempt may succeed when repeated.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay honors Retry-After, otherwise doubles retryBaseDelay per
+// attempt with jitter so that parallel fetches do not retry in lockstep.
+// The wait never exceeds the budget window.
+func retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if v := resp.Header.Get("Retry-After"); v != "" {
+			if secs, err := strconv.Atoi(v); err == nil {
+				return min(time.Duration(secs)*time.Second, budgetWindow)
+			}
+			if at, err := http.ParseTime(v); err == nil {
+				return min(max(time.Until(at), 0), budgetWindow)
+			}
+		}
+	}
+	d := min(retryBaseDelay<<attempt, budgetWindow)
+	return d/2 + rand.N(d/2+1)
+}
+
+// cancelBody releases the attempt's context once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// do sends the request built by newRequest, repeating it up to maxRetries
+// times while retryable. Every attempt is counted against the budget and
+// limited to timeout (0: no limit). The last response is returned as is.
+func (b *requestBudget) do(timeout time.Duration, newRequest func(ctx context.Context) *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		req := newRequest(ctx)
+		b.take()
+		resp, err := httpClient().Do(req)
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			b.rateLimited()
+		}
+		if attempt >= maxRetries || !retryable(resp, err) {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = cancelBody{resp.Body, cancel}
+			return resp, nil
+		}
+
+		delay := retryDelay(attempt, resp)
+		if err == nil {
+			log.Printf("[!] %s %s: статус %d, повтор через %s", req.Method, req.URL.Path, resp.StatusCode, delay.Round(time.Millisecond))
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		} else {
+			log.Printf("[!] %s %s: %v, повтор через %s", req.Method, req.URL.Path, err, delay.Round(time.Millisecond))
+		}
+		cancel()
+		retriesTotal.WithLabelValues(b.api).Inc()
+		time.Sleep(delay)
+	}
+}
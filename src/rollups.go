@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// rollup sources in the history store; the traffic gauges hold running
+// totals of their date label, so the daily value is their maximum.
+const (
+	rollupRequestsMetric = "cloudflare_zone_requests_total"
+	rollupStatusMetric   = "cloudflare_zone_status_code_requests_total"
+	rollupBytesMetric    = "cloudflare_zone_bandwidth_bytes_total"
+)
+
+const rollupSchema = `
+CREATE TABLE IF NOT EXISTS rollups (
+	period   TEXT    NOT NULL,
+	start    INTEGER NOT NULL,
+	zone     TEXT    NOT NULL,
+	requests REAL    NOT NULL,
+	bytes    REAL    NOT NULL,
+	errors   REAL    NOT NULL,
+	PRIMARY KEY (period, start, zone)
+);
+`
+
+var (
+	rollupRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_rollup_requests",
+			Help: "Requests of the last complete day or week from the local history store",
+		},
+		[]string{"zone_tag", "period"},
+	)
+
+	rollupBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_rollup_bytes",
+			Help: "Bytes of the last complete day or week from the local history store",
+		},
+		[]string{"zone_tag", "period"},
+	)
+
+	rollupErrorRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_rollup_error_ratio",
+			Help: "Share of 5xx responses of the last complete day or week from the local history store",
+		},
+		[]string{"zone_tag", "period"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(rollupRequests)
+	cycleRegistry.MustRegister(rollupBytes)
+	cycleRegistry.MustRegister(rollupErrorRatio)
+}
+
+type rollup struct {
+	Period   string  `json:"period"`
+	Start    int64   `json:"start"`
+	Zone     string  `json:"zone_tag"`
+	Requests float64 `json:"requests"`
+	Bytes    float64 `json:"bytes"`
+	Errors   float64 `json:"errors"`
+}
+
+// dailyMax sums, per zone, the maximum of every series of metric for the
+// day starting at start whose labels pass keep. Series with a date label
+// are re-reported while the date is within LOOKBACK_DAYS, so they are
+// matched by date; series without one by sample time.
+func dailyMax(metric string, start, end time.Time, keep func(labels string) bool) (map[string]float64, error) {
+	rows, err := historyDB.Query(
+		`SELECT zone, labels, MAX(value) FROM samples
+		WHERE metric = ? AND ts >= ? AND ts < ?
+		AND (labels LIKE ? OR (labels NOT LIKE '%"date":%' AND ts < ?))
+		GROUP BY zone, labels`,
+		metric, start.Unix(), end.AddDate(0, 0, lookbackDays).Unix(),
+		`%"date":"`+start.Format("2006-01-02")+`"%`, end.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]float64{}
+	for rows.Next() {
+		var zone, labels string
+		var value float64
+		if err := rows.Scan(&zone, &labels, &value); err != nil {
+			return nil, err
+		}
+		if keep == nil || keep(labels) {
+			out[zone] += value
+		}
+	}
+	return out, rows.Err()
+}
+
+func is5xxSeries(labels string) bool {
+	var l map[string]string
+	json.Unmarshal([]byte(labels), &l)
+	return strings.HasPrefix(l["status_code"], "5")
+}
+
+func dayRollups(day time.Time) (map[string]*rollup, error) {
+	end := day.AddDate(0, 0, 1)
+	out := map[string]*rollup{}
+	get := func(zone string) *rollup {
+		if out[zone] == nil {
+			out[zone] = &rollup{Period: "day", Start: day.Unix(), Zone: zone}
+		}
+		return out[zone]
+	}
+
+	requests, err := dailyMax(rollupRequestsMetric, day, end, nil)
+	if err != nil {
+		return nil, err
+	}
+	for zone, v := range requests {
+		get(zone).Requests = v
+	}
+	bytes, err := dailyMax(rollupBytesMetric, day, end, nil)
+	if err != nil {
+		return nil, err
+	}
+	for zone, v := range bytes {
+		get(zone).Bytes = v
+	}
+	errors, err := dailyMax(rollupStatusMetric, day, end, is5xxSeries)
+	if err != nil {
+		return nil, err
+	}
+	for zone, v := range errors {
+		get(zone).Errors = v
+	}
+	return out, nil
+}
+
+func saveRollup(r *rollup) error {
+	_, err := historyDB.Exec(
+		"INSERT OR REPLACE INTO rollups (period, start, zone, requests, bytes, errors) VALUES (?, ?, ?, ?, ?, ?)",
+		r.Period, r.Start, r.Zone, r.Requests, r.Bytes, r.Errors)
+	return err
+}
+
+func exportRollup(r *rollup) {
+	rollupRequests.WithLabelValues(r.Zone, r.Period).Set(r.Requests)
+	rollupBytes.WithLabelValues(r.Zone, r.Period).Set(r.Bytes)
+	if r.Requests > 0 {
+		rollupErrorRatio.WithLabelValues(r.Zone, r.Period).Set(r.Errors / r.Requests)
+	}
+}
+
+// updateRollups recomputes the last complete day and ISO week from the raw
+// samples, stores them and exports them as gauges.
+func updateRollups() {
+	if historyDB == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+	weekday := (int(today.Weekday()) + 6) % 7 // Monday = 0
+	weekStart := today.AddDate(0, 0, -weekday-7)
+
+	days, err := dayRollups(yesterday)
+	if err != nil {
+		log.Println("[!] Ошибка расчёта дневных rollups:", err)
+		return
+	}
+
+	week := map[string]*rollup{}
+	for i := 0; i < 7; i++ {
+		daily, err := dayRollups(weekStart.AddDate(0, 0, i))
+		if err != nil {
+			log.Println("[!] Ошибка расчёта недельных rollups:", err)
+			return
+		}
+		for zone, d := range daily {
+			w := week[zone]
+			if w == nil {
+				w = &rollup{Period: "week", Start: weekStart.Unix(), Zone: zone}
+				week[zone] = w
+			}
+			w.Requests += d.Requests
+			w.Bytes += d.Bytes
+			w.Errors += d.Errors
+		}
+	}
+
+	for _, m := range []*prometheus.GaugeVec{rollupRequests, rollupBytes, rollupErrorRatio} {
+		m.Reset()
+	}
+	for _, set := range []map[string]*rollup{days, week} {
+		for _, r := range set {
+			if err := saveRollup(r); err != nil {
+				log.Println("[!] Ошибка записи rollups:", err)
+				return
+			}
+			exportRollup(r)
+		}
+	}
+}
+
+// rollupsHandler serves /api/v1/rollups?period=day|week&zone=&from=&to=.
+func rollupsHandler(w http.ResponseWriter, r *http.Request) {
+	if historyDB == nil {
+		http.Error(w, "history store is disabled", http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	period := q.Get("period")
+	if period == "" {
+		period = "day"
+	}
+	to, err := parseHistoryTime(q.Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, "bad to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := parseHistoryTime(q.Get("from"), to.AddDate(-1, 0, 0))
+	if err != nil {
+		http.Error(w, "bad from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := "SELECT period, start, zone, requests, bytes, errors FROM rollups WHERE period = ? AND start >= ? AND start <= ?"
+	args := []any{period, from.Unix(), to.Unix()}
+	if zone := q.Get("zone"); zone != "" {
+		query += " AND zone = ?"
+		args = append(args, zone)
+	}
+	rows, err := historyDB.Query(query+" ORDER BY start", args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	rollups := []rollup{}
+	for rows.Next() {
+		var r rollup
+		if err := rows.Scan(&r.Period, &r.Start, &r.Zone, &r.Requests, &r.Bytes, &r.Errors); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rollups = append(rollups, r)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"rollups": rollups})
+}
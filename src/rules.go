@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	pageRulesMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_page_rules",
+			Help: "Number of page rules per zone by status",
+		},
+		[]string{"zone_tag", "status"},
+	)
+
+	pageRulesQuota = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_page_rules_quota",
+			Help: "Page rules allowed by the zone plan",
+		},
+		[]string{"zone_tag"},
+	)
+
+	rulesetRulesMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_ruleset_rules",
+			Help: "Number of rules in zone rulesets by phase",
+		},
+		[]string{"zone_tag", "phase"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(pageRulesMetric)
+	cycleRegistry.MustRegister(pageRulesQuota)
+	cycleRegistry.MustRegister(rulesetRulesMetric)
+
+	registerCollector(collector{
+		name:  "rules",
+		scope: "Page Rules",
+		zone:  fetchRuleCounts,
+	})
+}
+
+func fetchRuleCounts(zone Zone) error {
+	var details struct {
+		Meta struct {
+			PageRuleQuota int `json:"page_rule_quota"`
+		} `json:"meta"`
+	}
+	if _, err := cfGet("/zones/"+zone.ID, &details); err != nil {
+		return err
+	}
+
+	var pageRules []struct {
+		Status string `json:"status"`
+	}
+	if _, err := cfGet("/zones/"+zone.ID+"/pagerules", &pageRules); err != nil {
+		return err
+	}
+
+	var rulesets []struct {
+		ID    string `json:"id"`
+		Kind  string `json:"kind"`
+		Phase string `json:"phase"`
+	}
+	if _, err := cfGet("/zones/"+zone.ID+"/rulesets", &rulesets); err != nil {
+		return err
+	}
+
+	// only rulesets owned by the zone count against its quota, managed
+	// rulesets are listed too but belong to Cloudflare
+	byPhase := map[string]int{}
+	for _, rs := range rulesets {
+		if rs.Kind != "zone" {
+			continue
+		}
+		var ruleset struct {
+			Rules []struct {
+				ID string `json:"id"`
+			} `json:"rules"`
+		}
+		if _, err := cfGet("/zones/"+zone.ID+"/rulesets/"+rs.ID, &ruleset); err != nil {
+			return err
+		}
+		byPhase[rs.Phase] += len(ruleset.Rules)
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	pageRulesMetric.DeletePartialMatch(byZone)
+	rulesetRulesMetric.DeletePartialMatch(byZone)
+
+	byStatus := map[string]int{"active": 0, "disabled": 0}
+	for _, r := range pageRules {
+		byStatus[r.Status]++
+	}
+	for status, n := range byStatus {
+		pageRulesMetric.WithLabelValues(zone.Tag, status).Set(float64(n))
+	}
+	pageRulesQuota.WithLabelValues(zone.Tag).Set(float64(details.Meta.PageRuleQuota))
+	for phase, n := range byPhase {
+		rulesetRulesMetric.WithLabelValues(zone.Tag, phase).Set(float64(n))
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	rulesetRuleExecutions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_ruleset_rule_executions",
+			Help: "Ruleset rule matches per phase, rule and action over the adaptive window",
+		},
+		[]string{"zone_tag", "phase", "ruleset_id", "rule_id", "action"},
+	)
+
+	rulesetPhaseExecutions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_ruleset_phase_executions",
+			Help: "Ruleset rule matches per phase over the adaptive window",
+		},
+		[]string{"zone_tag", "phase"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(rulesetRuleExecutions)
+	cycleRegistry.MustRegister(rulesetPhaseExecutions)
+
+	registerCollector(collector{
+		name:     "ruleset_executions",
+		scope:    "Zone Analytics",
+		datasets: []string{"firewallEventsAdaptiveGroups"},
+		zone:     fetchRulesetExecutions,
+	})
+}
+
+// securityPhases are the ruleset phases whose rule matches are logged as
+// security events. Transform, cache, redirect and origin rules log none, so
+// their phases are not exported rather than read as 0 matches.
+var securityPhases = map[string]bool{
+	"http_request_firewall_custom":  true,
+	"http_request_firewall_managed": true,
+	"http_ratelimit":                true,
+	"http_request_sbfm":             true,
+	"ddos_l7":                       true,
+}
+
+// Rule matches are read from the security events, see securityPhases.
+const rulesetExecutionsQuery = `query ($zoneTag: string!, $filter: ZoneFirewallEventsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      firewallEventsAdaptiveGroups(filter: $filter, limit: 1000, orderBy: [count_DESC]) {
+        count
+        dimensions { rulesetId ruleId action }
+      }
+    }
+  }
+}`
+
+func fetchRulesetExecutions(zone Zone) error {
+	var rulesets []struct {
+		ID    string `json:"id"`
+		Phase string `json:"phase"`
+	}
+	if _, err := cfGet("/zones/"+zone.ID+"/rulesets", &rulesets); err != nil {
+		return err
+	}
+	phases := map[string]string{}
+	for _, rs := range rulesets {
+		if securityPhases[rs.Phase] {
+			phases[rs.ID] = rs.Phase
+		}
+	}
+
+	filter := adaptiveFilter()
+	filter["rulesetId_neq"] = ""
+	z, err := queryZone[struct {
+		FirewallEventsAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				RulesetID string `json:"rulesetId"`
+				RuleID    string `json:"ruleId"`
+				Action    string `json:"action"`
+			} `json:"dimensions"`
+		} `json:"firewallEventsAdaptiveGroups"`
+	}](zone, rulesetExecutionsQuery, map[string]any{
+		"filter": zoneFilter(zone, "firewallEventsAdaptiveGroups", filter),
+	})
+	if err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	rulesetRuleExecutions.DeletePartialMatch(byZone)
+	rulesetPhaseExecutions.DeletePartialMatch(byZone)
+	for _, phase := range phases {
+		rulesetPhaseExecutions.WithLabelValues(zone.Tag, phase)
+	}
+	if z == nil {
+		return nil
+	}
+
+	for _, g := range z.FirewallEventsAdaptiveGroups {
+		phase := phases[g.Dimensions.RulesetID]
+		if phase == "" {
+			// account level rulesets deployed to the zone are not listed
+			phase = "unknown"
+		}
+		rulesetRuleExecutions.WithLabelValues(zone.Tag, phase, g.Dimensions.RulesetID, g.Dimensions.RuleID, g.Dimensions.Action).Add(g.Count)
+		rulesetPhaseExecutions.WithLabelValues(zone.Tag, phase).Add(g.Count)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scaleSampledCounts multiplies adaptive group counts by their average
+// sample interval. Cloudflare normally returns counts already estimated
+// for the whole traffic, so this is only for datasets where it doesn't.
+var scaleSampledCounts = false
+
+var (
+	sampleIntervalMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_sample_interval",
+			Help: "Average sample interval of an adaptive dataset over the adaptive window, 1 means unsampled",
+		},
+		[]string{"zone_tag", "dataset"},
+	)
+
+	samplingRatioMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_sampling_ratio",
+			Help: "Share of events an adaptive dataset kept over the adaptive window (1 / sample interval)",
+		},
+		[]string{"zone_tag", "dataset"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(sampleIntervalMetric)
+	cycleRegistry.MustRegister(samplingRatioMetric)
+
+	registerCollector(collector{
+		name:     "sampling",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups", "firewallEventsAdaptiveGroups"},
+		zone:     fetchSampling,
+	})
+}
+
+const samplingQuery = `query ($zoneTag: string!, $requests: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!, $events: ZoneFirewallEventsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      httpRequestsAdaptiveGroups(filter: $requests, limit: 1) { avg { sampleInterval } }
+      firewallEventsAdaptiveGroups(filter: $events, limit: 1) { avg { sampleInterval } }
+    }
+  }
+}`
+
+type sampledGroup struct {
+	Avg struct {
+		SampleInterval float64 `json:"sampleInterval"`
+	} `json:"avg"`
+}
+
+// sampledCount returns count scaled by the group's sample interval when
+// SCALE_SAMPLED_COUNTS is enabled.
+func sampledCount(count float64, g sampledGroup) float64 {
+	if scaleSampledCounts && g.Avg.SampleInterval > 1 {
+		return count * g.Avg.SampleInterval
+	}
+	return count
+}
+
+func fetchSampling(zone Zone) error {
+	z, err := queryZone[map[string][]sampledGroup](zone, samplingQuery, map[string]any{
+		"requests": zoneFilter(zone, "httpRequestsAdaptiveGroups", adaptiveFilter()),
+		"events":   zoneFilter(zone, "firewallEventsAdaptiveGroups", adaptiveFilter()),
+	})
+	if err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	sampleIntervalMetric.DeletePartialMatch(byZone)
+	samplingRatioMetric.DeletePartialMatch(byZone)
+	if z == nil {
+		return nil
+	}
+	// datasets without events in the window have no interval
+	for dataset, groups := range *z {
+		if len(groups) == 0 || groups[0].Avg.SampleInterval <= 0 {
+			continue
+		}
+		interval := groups[0].Avg.SampleInterval
+		sampleIntervalMetric.WithLabelValues(zone.Tag, dataset).Set(interval)
+		samplingRatioMetric.WithLabelValues(zone.Tag, dataset).Set(1 / interval)
+	}
+	return nil
+}
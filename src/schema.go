@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// unknownFieldPattern matches the GraphQL errors about fields missing
+	// from the schema.
+	unknownFieldPattern = regexp.MustCompile(`(?i)(?:unknown field|cannot query field) "(\w+)"`)
+	// emptySelection matches a field left without subfields.
+	emptySelection = regexp.MustCompile(`\b\w+(\s*:\s*\w+)?(\s*\([^()]*\))?\s*\{\s*\}`)
+
+	disabledFields      = map[string]bool{}
+	disabledFieldsMutex = &sync.RWMutex{}
+
+	disabledFieldMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_exporter_graphql_field_disabled",
+			Help: "1 for GraphQL fields dropped from all queries after Cloudflare reported them unknown",
+		},
+		[]string{"field"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(disabledFieldMetric)
+}
+
+// disableUnknownField checks err for unknown-field errors, disables those
+// fields and returns query without them. It returns false when nothing
+// could be removed, the error is then final.
+func disableUnknownField(query string, err error) (string, bool) {
+	var gqlErrs graphqlErrors
+	if !errors.As(err, &gqlErrs) {
+		return query, false
+	}
+	removed := false
+	for _, e := range gqlErrs {
+		m := unknownFieldPattern.FindStringSubmatch(e.Message)
+		if m == nil {
+			continue
+		}
+		stripped, ok := removeField(query, m[1])
+		if !ok {
+			continue
+		}
+		query, removed = stripped, true
+
+		disabledFieldsMutex.Lock()
+		if !disabledFields[m[1]] {
+			disabledFields[m[1]] = true
+			log.Printf("[!] Cloudflare не знает поле %s, убираем его из запросов: %s", m[1], e.Message)
+		}
+		disabledFieldsMutex.Unlock()
+		disabledFieldMetric.WithLabelValues(m[1]).Set(1)
+	}
+	return query, removed
+}
+
+// withoutDisabledFields removes the fields disabled so far from query.
+func withoutDisabledFields(query string) string {
+	disabledFieldsMutex.RLock()
+	fields := make([]string, 0, len(disabledFields))
+	for field := range disabledFields {
+		fields = append(fields, field)
+	}
+	disabledFieldsMutex.RUnlock()
+	sort.Strings(fields)
+	for _, field := range fields {
+		query, _ = removeField(query, field)
+	}
+	return query
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r' || s[i] == ',') {
+		i++
+	}
+	return i
+}
+
+// skipBalanced returns the index after the bracket closing the one at i.
+func skipBalanced(s string, i int, open, close byte) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// removeField deletes every selection of field from query, together with
+// its alias, arguments and subfields. Names inside arguments and variable
+// definitions are not touched. Selections left empty are removed as well.
+func removeField(query, field string) (string, bool) {
+	var b strings.Builder
+	removed := false
+	parens := 0
+	for i := 0; i < len(query); {
+		c := query[i]
+		if c == '(' {
+			parens++
+		} else if c == ')' {
+			parens--
+		}
+		if parens > 0 || !isNameChar(c) || i > 0 && isNameChar(query[i-1]) {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(query) && isNameChar(query[end]) {
+			end++
+		}
+		name := query[i:end]
+		// "alias: field" selects field
+		if k := skipSpace(query, end); k < len(query) && query[k] == ':' {
+			start := skipSpace(query, k+1)
+			stop := start
+			for stop < len(query) && isNameChar(query[stop]) {
+				stop++
+			}
+			name, end = query[start:stop], stop
+		}
+		if name != field {
+			b.WriteString(query[i:end])
+			i = end
+			continue
+		}
+
+		if k := skipSpace(query, end); k < len(query) && query[k] == '(' {
+			end = skipBalanced(query, k, '(', ')')
+		}
+		if k := skipSpace(query, end); k < len(query) && query[k] == '{' {
+			end = skipBalanced(query, k, '{', '}')
+		}
+		removed = true
+		i = end
+	}
+	if !removed {
+		return query, false
+	}
+
+	query = b.String()
+	for {
+		stripped := emptySelection.ReplaceAllString(query, "")
+		if stripped == query {
+			return query, true
+		}
+		query = stripped
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemoveField(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		field   string
+		want    string
+		removed bool
+	}{
+		{
+			name:    "plain field",
+			query:   "{ sum { requests bytes threats } }",
+			field:   "bytes",
+			want:    "{ sum { requests  threats } }",
+			removed: true,
+		},
+		{
+			name:    "field with subfields",
+			query:   "{ sum { requests responseStatusMap { edgeResponseStatus requests } } }",
+			field:   "responseStatusMap",
+			want:    "{ sum { requests  } }",
+			removed: true,
+		},
+		{
+			name:    "aliased field with arguments",
+			query:   "{ zones { current: httpRequests1hGroups(limit: 10) { count } other { count } } }",
+			field:   "httpRequests1hGroups",
+			want:    "{ zones {  other { count } } }",
+			removed: true,
+		},
+		{
+			name:    "emptied selection is removed",
+			query:   "{ zones { uniq { uniques } count } }",
+			field:   "uniques",
+			want:    "{ zones {  count } }",
+			removed: true,
+		},
+		{
+			name:    "names in arguments stay",
+			query:   "query ($bytes: uint64!) { groups(filter: { bytes_gt: $bytes }) { count } }",
+			field:   "bytes",
+			want:    "query ($bytes: uint64!) { groups(filter: { bytes_gt: $bytes }) { count } }",
+			removed: false,
+		},
+		{
+			name:    "prefix of another field",
+			query:   "{ sum { bytesCached requests } }",
+			field:   "bytes",
+			want:    "{ sum { bytesCached requests } }",
+			removed: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed := removeField(tt.query, tt.field)
+			if removed != tt.removed {
+				t.Fatalf("removed = %v, want %v", removed, tt.removed)
+			}
+			if strings.Join(strings.Fields(got), " ") != strings.Join(strings.Fields(tt.want), " ") {
+				t.Errorf("removeField(%q, %q) = %q, want %q", tt.query, tt.field, got, tt.want)
+			}
+		})
+	}
+}
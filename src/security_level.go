@@ -0,0 +1,57 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	securityLevels = []string{"off", "essentially_off", "low", "medium", "high", "under_attack"}
+
+	securityLevelMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_security_level",
+			Help: "1 for the current security level of the zone, under_attack is I'm Under Attack mode",
+		},
+		[]string{"zone_tag", "level"},
+	)
+
+	underAttackSince = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_under_attack_since_timestamp_seconds",
+			Help: "Last change of the security level while the zone is in I'm Under Attack mode, absent otherwise",
+		},
+		[]string{"zone_tag"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(securityLevelMetric)
+	cycleRegistry.MustRegister(underAttackSince)
+
+	registerCollector(collector{
+		name:  "security_level",
+		scope: "Zone Settings",
+		zone:  fetchSecurityLevel,
+	})
+}
+
+func fetchSecurityLevel(zone Zone) error {
+	var setting struct {
+		Value      string     `json:"value"`
+		ModifiedOn *time.Time `json:"modified_on"`
+	}
+	if _, err := cfGet("/zones/"+zone.ID+"/settings/security_level", &setting); err != nil {
+		return err
+	}
+	for _, level := range securityLevels {
+		securityLevelMetric.WithLabelValues(zone.Tag, level).Set(boolToFloat(setting.Value == level))
+	}
+
+	underAttackSince.DeleteLabelValues(zone.Tag)
+	if setting.Value == "under_attack" && setting.ModifiedOn != nil {
+		underAttackSince.WithLabelValues(zone.Tag).Set(float64(setting.ModifiedOn.Unix()))
+	}
+	return nil
+}
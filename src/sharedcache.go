@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/redis/go-redis/v9"
+)
+
+// With REDIS_URL set, replicas elect a leader through a Redis lock. Only the
+// leader queries Cloudflare and publishes its metrics to Redis; followers
+// serve the published metrics.
+var (
+	redisURL       = ""
+	redisKeyPrefix = "cf-collector"
+	redisLockTTL   = 30 * time.Second
+
+	redisClient *redis.Client
+	instanceID  string
+	leader      atomic.Bool
+
+	leaderMetric = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudflare_exporter_leader",
+		Help: "1 if this replica is the leader collecting from Cloudflare",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(leaderMetric)
+}
+
+func redisKey(name string) string {
+	return redisKeyPrefix + ":" + name
+}
+
+// openSharedCache connects to Redis and starts the leader election. Without
+// REDIS_URL the instance is always the leader.
+func openSharedCache() error {
+	if redisURL == "" {
+		leader.Store(true)
+		leaderMetric.Set(1)
+		return nil
+	}
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return err
+	}
+	redisClient = redis.NewClient(opts)
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	instanceID = fmt.Sprintf("%s-%d", host, os.Getpid())
+
+	campaign()
+	go func() {
+		for range time.Tick(redisLockTTL / 3) {
+			campaign()
+		}
+	}()
+	log.Println("[OK] Shared cache:", opts.Addr, "instance:", instanceID)
+	return nil
+}
+
+// renewScript extends the lock only if this instance still holds it.
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// campaign acquires or renews the leader lock.
+func campaign() {
+	ctx := context.Background()
+	key := redisKey("leader")
+	ok, err := redisClient.SetNX(ctx, key, instanceID, redisLockTTL).Result()
+	if err == nil && !ok {
+		var renewed int64
+		renewed, err = renewScript.Run(ctx, redisClient, []string{key}, instanceID, redisLockTTL.Milliseconds()).Int64()
+		ok = renewed == 1
+	}
+	if err != nil {
+		log.Println("[!] Ошибка блокировки лидера в Redis:", err)
+		ok = false
+	}
+	if ok != leader.Load() {
+		if ok {
+			log.Println("[OK] Became leader")
+		} else {
+			log.Println("[OK] Following", redisClient.Get(ctx, key).Val())
+		}
+	}
+	leader.Store(ok)
+	leaderMetric.Set(boolToFloat(ok))
+}
+
+func isLeader() bool {
+	return leader.Load()
+}
+
+// publishMetrics stores the leader's collected metrics for the followers.
+// Only the cycle snapshot is shared: exporter metrics such as the leader
+// flag, readiness and the Go runtime stay per replica.
+func publishMetrics() {
+	if redisClient == nil || !isLeader() {
+		return
+	}
+	families, err := snapshotGatherer{}.Gather()
+	if err != nil {
+		log.Println("[!] Ошибка сбора метрик для Redis:", err)
+	}
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			log.Println("[!] Ошибка кодирования метрик для Redis:", err)
+			return
+		}
+	}
+	// keep the metrics for a few cycles so followers survive a slow leader
+	ttl := 3*scrapeInterval + redisLockTTL
+	if err := redisClient.Set(context.Background(), redisKey("metrics"), buf.Bytes(), ttl).Err(); err != nil {
+		log.Println("[!] Ошибка записи метрик в Redis:", err)
+	}
+}
+
+// sharedGatherer serves local metrics on the leader, and on followers the
+// snapshot published by the leader together with their own exporter
+// metrics.
+type sharedGatherer struct {
+	prometheus.Gatherer
+}
+
+func (g sharedGatherer) Gather() ([]*dto.MetricFamily, error) {
+	if redisClient == nil || isLeader() {
+		return g.Gatherer.Gather()
+	}
+	return prometheus.Gatherers{redisSnapshotGatherer{}, prometheus.DefaultGatherer}.Gather()
+}
+
+// redisSnapshotGatherer reads the cycle snapshot published by the leader.
+type redisSnapshotGatherer struct{}
+
+func (redisSnapshotGatherer) Gather() ([]*dto.MetricFamily, error) {
+	data, err := redisClient.Get(context.Background(), redisKey("metrics")).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	families := make([]*dto.MetricFamily, 0, len(parsed))
+	for _, mf := range parsed {
+		families = append(families, mf)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families, nil
+}
+
+type sharedZones struct {
+	Zones    []Zone    `json:"zones"`
+	Accounts []Account `json:"accounts"`
+}
+
+// discoverZones lists the zones from Cloudflare on the leader and shares
+// the result, followers take the leader's list instead of calling /zones.
+func discoverZones() error {
+	if redisClient == nil {
+		return assignAllZones()
+	}
+	for !isLeader() {
+		err := loadSharedZones()
+		if err == nil {
+			return nil
+		}
+		log.Println("[!] Список зон ещё не опубликован лидером:", err)
+		time.Sleep(redisLockTTL / 3)
+	}
+	if err := assignAllZones(); err != nil {
+		return err
+	}
+	publishZones()
+	return nil
+}
+
+func publishZones() {
+	zonesMutex.RLock()
+	data, err := json.Marshal(sharedZones{zones, accounts})
+	zonesMutex.RUnlock()
+	if err != nil {
+		return
+	}
+	if err := redisClient.Set(context.Background(), redisKey("zones"), data, 0).Err(); err != nil {
+		log.Println("[!] Ошибка записи списка зон в Redis:", err)
+	}
+}
+
+func loadSharedZones() error {
+	data, err := redisClient.Get(context.Background(), redisKey("zones")).Bytes()
+	if err != nil {
+		return err
+	}
+	var shared sharedZones
+	if err := json.Unmarshal(data, &shared); err != nil {
+		return err
+	}
+	zonesMutex.Lock()
+	zones = shared.Zones
+	accounts = shared.Accounts
+	indexTokens()
+	zonesMutex.Unlock()
+	return nil
+}
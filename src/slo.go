@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sloTarget = 0.999
+
+	availabilityMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_availability",
+			Help: "1 - 5xx/requests of the current day per zone",
+		},
+		[]string{"zone_tag"},
+	)
+
+	sloTargetMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_slo_target",
+			Help: "Availability SLO target of the zone",
+		},
+		[]string{"zone_tag"},
+	)
+
+	errorBudgetMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_error_budget_remaining",
+			Help: "Share of the current day's error budget left (negative when exceeded)",
+		},
+		[]string{"zone_tag"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(availabilityMetric)
+	cycleRegistry.MustRegister(sloTargetMetric)
+	cycleRegistry.MustRegister(errorBudgetMetric)
+}
+
+// zoneSLOTarget returns the slo_target of the last matching zones entry,
+// or the global SLO_TARGET.
+func zoneSLOTarget(zone Zone) float64 {
+	target := sloTarget
+	for _, zc := range zoneConfigs(zone) {
+		if zc.SLOTarget != nil {
+			target = *zc.SLOTarget
+		}
+	}
+	return target
+}
+
+// updateSLO is called by the traffic collector with the day's totals.
+func updateSLO(zone Zone, requests, errors float64) {
+	if requests <= 0 {
+		return
+	}
+	target := zoneSLOTarget(zone)
+	errorRate := errors / requests
+	availabilityMetric.WithLabelValues(zone.Tag).Set(1 - errorRate)
+	sloTargetMetric.WithLabelValues(zone.Tag).Set(target)
+	if target < 1 {
+		errorBudgetMetric.WithLabelValues(zone.Tag).Set(1 - errorRate/(1-target))
+	}
+}
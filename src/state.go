@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statePath is a JSON file with runtime changes made through the admin API,
+// re-applied after zone discovery on start.
+var statePath = ""
+
+type collectorState struct {
+	// AddedZones are zone names added at runtime; they are looked up by
+	// name if discovery doesn't return them.
+	AddedZones map[string]bool `json:"added_zones"`
+	// RemovedZones are dropped from discovery results.
+	RemovedZones map[string]bool `json:"removed_zones"`
+	PausedZones  map[string]bool `json:"paused_zones"`
+}
+
+var (
+	state = collectorState{
+		AddedZones:   map[string]bool{},
+		RemovedZones: map[string]bool{},
+		PausedZones:  map[string]bool{},
+	}
+	stateMutex = &sync.RWMutex{}
+
+	zonePausedMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_paused",
+			Help: "1 if collection is paused for the zone",
+		},
+		[]string{"zone_tag"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(zonePausedMetric)
+}
+
+func loadState() error {
+	if statePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	for _, m := range []*map[string]bool{&state.AddedZones, &state.RemovedZones, &state.PausedZones} {
+		if *m == nil {
+			*m = map[string]bool{}
+		}
+	}
+	return nil
+}
+
+// saveState writes the state file atomically. The caller must hold
+// stateMutex.
+func saveState() error {
+	if statePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, statePath)
+}
+
+// zonePaused reports whether the zone is paused through the admin API or by
+// a matching zones entry of the config.
+func zonePaused(zone Zone) bool {
+	stateMutex.RLock()
+	paused := state.PausedZones[zone.Tag]
+	stateMutex.RUnlock()
+	for _, zc := range zoneConfigs(zone) {
+		paused = paused || zc.Paused
+	}
+	return paused
+}
+
+// lookupZone finds an active zone by name.
+func lookupZone(name string) (Zone, Account, error) {
+	var result []struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Status  string `json:"status"`
+		Account struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"account"`
+	}
+	for i, token := range apiTokens() {
+		if _, err := cfGetToken(token, "/zones?name="+url.QueryEscape(name), &result); err != nil {
+			return Zone{}, Account{}, err
+		}
+		for _, z := range result {
+			if z.Name == name && z.Status == "active" {
+				return Zone{Tag: z.Name, ID: z.ID, AccountID: z.Account.ID, Token: i}, Account{ID: z.Account.ID, Name: z.Account.Name, Token: i}, nil
+			}
+		}
+	}
+	return Zone{}, Account{}, errors.New("active zone not found: " + name)
+}
+
+// applyZoneState removes and adds the zones changed at runtime to a
+// discovery result.
+func applyZoneState(found []Zone, owners []Account) ([]Zone, []Account) {
+	stateMutex.RLock()
+	defer stateMutex.RUnlock()
+
+	result := []Zone{}
+	seen := map[string]bool{}
+	for _, zone := range found {
+		if state.RemovedZones[zone.Tag] {
+			continue
+		}
+		seen[zone.Tag] = true
+		result = append(result, zone)
+	}
+	for name := range state.AddedZones {
+		if seen[name] {
+			continue
+		}
+		zone, account, err := lookupZone(name)
+		if err != nil {
+			log.Printf("[!] Ошибка добавления зоны %s: %v", name, err)
+			continue
+		}
+		result = append(result, zone)
+		owners = append(owners, account)
+	}
+	return result, zoneAccounts(result, owners)
+}
+
+// zoneAccounts keeps the accounts owning at least one of the zones, once
+// each.
+func zoneAccounts(zones []Zone, owners []Account) []Account {
+	owned := map[string]bool{}
+	for _, zone := range zones {
+		owned[zone.AccountID] = true
+	}
+	result := []Account{}
+	for _, account := range owners {
+		if owned[account.ID] {
+			result = append(result, account)
+			owned[account.ID] = false
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fetchStatus is the outcome of the last run of a collector for one zone
+// or account.
+type fetchStatus struct {
+	LastFetch time.Time
+	Duration  time.Duration
+	LastError string
+	// LastSuccess is zero until the collector succeeded once.
+	LastSuccess time.Time
+}
+
+type fetchKey struct {
+	collector string
+	// target is the zone name or "account:<name>"
+	target string
+}
+
+var (
+	fetchStatuses      = map[fetchKey]fetchStatus{}
+	fetchStatusesMutex = &sync.RWMutex{}
+)
+
+func recordFetch(collector, target string, start time.Time, err error) {
+	fetchStatusesMutex.Lock()
+	defer fetchStatusesMutex.Unlock()
+
+	key := fetchKey{collector, target}
+	s := fetchStatuses[key]
+	s.LastFetch = start
+	s.Duration = time.Since(start)
+	failing := s.LastError != ""
+	s.LastError = ""
+	if err != nil {
+		s.LastError = err.Error()
+		collectErrors.WithLabelValues(target, collector, errorReason(err)).Inc()
+	} else {
+		s.LastSuccess = start
+	}
+	fetchStatuses[key] = s
+
+	switch {
+	case err != nil && !failing:
+		kubeEvent("Warning", "CollectorFailing", fmt.Sprintf("Collector %s failing for %s: %v", collector, target, err))
+	case err == nil && failing:
+		kubeEvent("Normal", "CollectorRecovered", fmt.Sprintf("Collector %s recovered for %s", collector, target))
+	}
+}
+
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>cf-metrics-collector status</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+.ok { color: #2a2; }
+.err { color: #c22; }
+</style>
+</head>
+<body>
+<h1>Collection status</h1>
+<p>{{len .Zones}} zones, {{len .Accounts}} accounts, enabled collectors: {{range $i, $c := .Collectors}}{{if $i}}, {{end}}{{$c}}{{end}}</p>
+{{range .Targets}}
+<h2>{{.Name}}{{if .Paused}} (paused){{end}}</h2>
+<table>
+<tr><th>collector</th><th>state</th><th>last fetch</th><th>duration</th><th>last success</th><th>last error</th></tr>
+{{range .Rows}}
+<tr>
+<td>{{.Collector}}</td>
+{{if .Status.LastFetch.IsZero}}<td>pending</td>{{else if .Status.LastError}}<td class="err">down</td>{{else}}<td class="ok">up</td>{{end}}
+<td>{{if not .Status.LastFetch.IsZero}}{{.Status.LastFetch.Format "2006-01-02 15:04:05 MST"}}{{end}}</td>
+<td>{{if not .Status.LastFetch.IsZero}}{{.Status.Duration}}{{end}}</td>
+<td>{{if not .Status.LastSuccess.IsZero}}{{.Status.LastSuccess.Format "2006-01-02 15:04:05 MST"}}{{end}}</td>
+<td class="err">{{.Status.LastError}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+// statusHandler serves /status, an overview of the last fetch of every
+// enabled collector per zone and account, similar to Prometheus /targets.
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	type row struct {
+		Collector string
+		Status    fetchStatus
+	}
+	type target struct {
+		Name   string
+		Paused bool
+		Rows   []row
+	}
+	page := struct {
+		Zones      []Zone
+		Accounts   []Account
+		Collectors []string
+		Targets    []target
+	}{}
+
+	zonesMutex.RLock()
+	page.Zones = append(page.Zones, zones...)
+	page.Accounts = append(page.Accounts, accounts...)
+	zonesMutex.RUnlock()
+
+	fetchStatusesMutex.RLock()
+	defer fetchStatusesMutex.RUnlock()
+
+	rowsFor := func(name string, byZone bool) []row {
+		rows := []row{}
+		for _, c := range collectors {
+			if !enabledCollectors[c.name] || (byZone && c.zone == nil) || (!byZone && c.account == nil) {
+				continue
+			}
+			rows = append(rows, row{c.name, fetchStatuses[fetchKey{c.name, name}]})
+		}
+		return rows
+	}
+	for _, account := range page.Accounts {
+		if rows := rowsFor("account:"+account.Name, false); len(rows) > 0 {
+			page.Targets = append(page.Targets, target{"account " + account.Name, false, rows})
+		}
+	}
+	for _, zone := range page.Zones {
+		if rows := rowsFor(zone.Tag, true); len(rows) > 0 {
+			page.Targets = append(page.Targets, target{zone.Tag, zonePaused(zone), rows})
+		}
+	}
+	for name := range enabledCollectors {
+		page.Collectors = append(page.Collectors, name)
+	}
+	sort.Strings(page.Collectors)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	statusTemplate.Execute(w, page)
+}
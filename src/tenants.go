@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type tenantConfig struct {
+	// Token, when set, must be sent as "Authorization: Bearer <token>".
+	Token string `yaml:"token"`
+}
+
+// zoneTenant returns the tenant of the last matching zones entry that
+// sets one.
+func zoneTenant(zone Zone) string {
+	tenant := ""
+	for _, zc := range zoneConfigs(zone) {
+		if zc.Tenant != "" {
+			tenant = zc.Tenant
+		}
+	}
+	return tenant
+}
+
+func tenantKnown(tenant string) bool {
+	if _, ok := config.Tenants[tenant]; ok {
+		return true
+	}
+	for _, zc := range config.Zones {
+		if zc.Tenant == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantGatherer keeps only the zone series of one tenant. Series without
+// zone_tag (account metrics, exporter internals) are never exposed to
+// tenants.
+type tenantGatherer struct {
+	prometheus.Gatherer
+	tenant string
+}
+
+func (g tenantGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+
+	owned := map[string]bool{}
+	filtered := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		metrics := mf.Metric[:0]
+		for _, m := range mf.Metric {
+			for _, lp := range m.Label {
+				if lp.GetName() != "zone_tag" {
+					continue
+				}
+				zoneTag := lp.GetValue()
+				ok, seen := owned[zoneTag]
+				if !seen {
+					ok = zoneTenant(Zone{Tag: zoneTag}) == g.tenant
+					owned[zoneTag] = ok
+				}
+				if ok {
+					metrics = append(metrics, m)
+				}
+				break
+			}
+		}
+		if len(metrics) > 0 {
+			mf.Metric = metrics
+			filtered = append(filtered, mf)
+		}
+	}
+	return filtered, err
+}
+
+// tenantMetricsHandler serves /metrics/tenants/{tenant}.
+func tenantMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := r.PathValue("tenant")
+	if !tenantKnown(tenant) {
+		http.NotFound(w, r)
+		return
+	}
+	if token := config.Tenants[tenant].Token; token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	gatherer := tenantGatherer{externalLabelsGatherer{zoneLabelsGatherer{sharedGatherer{exposedGatherer}}}, tenant}
+	promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var threatsByCountryMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cloudflare_zone_threats_by_country",
+		Help: "Threats per zone by client country for the current day (GraphQL 1dGroups API)",
+	},
+	[]string{"zone_tag", "country"},
+)
+
+func init() {
+	cycleRegistry.MustRegister(threatsByCountryMetric)
+
+	registerCollector(collector{
+		name:     "threats_country",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequests1dGroups"},
+		zone:     fetchThreatsByCountry,
+	})
+}
+
+const threatsByCountryQuery = `query ($zoneTag: string!, $filter: ZoneHttpRequests1dGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      httpRequests1dGroups(filter: $filter, limit: 1) {
+        sum { countryMap { clientCountryName threats } }
+      }
+    }
+  }
+}`
+
+func fetchThreatsByCountry(zone Zone) error {
+	today := time.Now().UTC().Format("2006-01-02")
+	z, err := queryZone[struct {
+		HttpRequests1dGroups []struct {
+			Sum struct {
+				CountryMap []struct {
+					ClientCountryName string  `json:"clientCountryName"`
+					Threats           float64 `json:"threats"`
+				} `json:"countryMap"`
+			} `json:"sum"`
+		} `json:"httpRequests1dGroups"`
+	}](zone, threatsByCountryQuery, map[string]any{"filter": zoneFilter(zone, "httpRequests1dGroups", map[string]any{"date": today})})
+	if err != nil {
+		return err
+	}
+
+	threatsByCountryMetric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag})
+	if z == nil {
+		return nil
+	}
+	for _, group := range z.HttpRequests1dGroups {
+		for _, c := range group.Sum.CountryMap {
+			if c.Threats > 0 {
+				threatsByCountryMetric.WithLabelValues(zone.Tag, c.ClientCountryName).Set(c.Threats)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// apiTokensList holds further tokens, comma-separated, for accounts the
+// main CLOUDFLARE_API_TOKEN can't see.
+var apiTokensList = ""
+
+// apiTokens returns the main token followed by the extra ones.
+func apiTokens() []string {
+	tokens := []string{}
+	seen := map[string]bool{}
+	for _, token := range append([]string{apiToken}, strings.Split(apiTokensList, ",")...) {
+		token = strings.TrimSpace(token)
+		if token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+var (
+	// tokenIndex maps zone and account IDs to the index of the token
+	// that discovered them.
+	tokenIndex      = map[string]int{}
+	tokenIndexMutex = &sync.RWMutex{}
+
+	tokenPathPattern = regexp.MustCompile(`^/(?:zones|accounts)/([0-9a-f]+)|[?&]account\.id=([0-9a-f]+)`)
+)
+
+// indexTokens remembers the token of every zone and account. The caller
+// holds zonesMutex.
+func indexTokens() {
+	index := map[string]int{}
+	for _, zone := range zones {
+		index[zone.ID] = zone.Token
+	}
+	for _, account := range accounts {
+		index[account.ID] = account.Token
+	}
+	tokenIndexMutex.Lock()
+	tokenIndex = index
+	tokenIndexMutex.Unlock()
+}
+
+// tokenFor picks the token of the zone or account a request is about, from
+// the REST path or the zoneTag/accountTag GraphQL variable. Other requests
+// use the main token.
+func tokenFor(path string, variables map[string]any) string {
+	tokens := apiTokens()
+	if len(tokens) == 0 {
+		return ""
+	}
+	id := ""
+	if m := tokenPathPattern.FindStringSubmatch(path); m != nil {
+		id = m[1] + m[2]
+	} else if tag, ok := variables["zoneTag"].(string); ok {
+		id = tag
+	} else if tag, ok := variables["accountTag"].(string); ok {
+		id = tag
+	}
+
+	tokenIndexMutex.RLock()
+	i, ok := tokenIndex[id]
+	tokenIndexMutex.RUnlock()
+	if !ok || i >= len(tokens) {
+		return tokens[0]
+	}
+	return tokens[i]
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// maxTopN caps every top-N collector regardless of configuration.
+	maxTopN = 50
+	// maxTopLabelLength truncates dimension values used as label values.
+	maxTopLabelLength = 128
+	// topOtherLabel holds the requests not covered by the top-N entries.
+	topOtherLabel = "__other__"
+)
+
+var (
+	topPathsLimit      = 10
+	topReferersLimit   = 10
+	topUserAgentsLimit = 20
+
+	topPathsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_top_path_requests",
+			Help: "Requests for the top N request paths per zone over the adaptive window",
+		},
+		[]string{"zone_tag", "path"},
+	)
+
+	topReferersMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_top_referer_requests",
+			Help: "Requests for the top N referer hosts per zone over the adaptive window",
+		},
+		[]string{"zone_tag", "referer"},
+	)
+
+	topUserAgentsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_top_user_agent_requests",
+			Help: "Requests for the top N normalized user agents per zone over the adaptive window",
+		},
+		[]string{"zone_tag", "user_agent"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(topPathsMetric)
+	cycleRegistry.MustRegister(topReferersMetric)
+	cycleRegistry.MustRegister(topUserAgentsMetric)
+
+	registerCollector(collector{
+		name:     "top_paths",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups"},
+		zone: func(zone Zone) error {
+			return fetchTopN(zone, "clientRequestPath", topPathsLimit, nil, topPathsMetric)
+		},
+	})
+	registerCollector(collector{
+		name:     "top_referers",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups"},
+		zone: func(zone Zone) error {
+			return fetchTopN(zone, "clientRefererHost", topReferersLimit, normalizeReferer, topReferersMetric)
+		},
+	})
+	registerCollector(collector{
+		name:     "top_user_agents",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups"},
+		zone: func(zone Zone) error {
+			return fetchTopN(zone, "userAgent", topUserAgentsLimit, normalizeUserAgent, topUserAgentsMetric)
+		},
+	})
+}
+
+func normalizeReferer(host string) string {
+	if host == "" {
+		return "direct"
+	}
+	return strings.ToLower(strings.TrimPrefix(host, "www."))
+}
+
+type topEntry struct {
+	Value string
+	Count float64
+}
+
+// queryTopN returns the n most frequent values of an httpRequestsAdaptiveGroups
+// dimension together with the total request count of the window.
+func queryTopN(zone Zone, dimension string, n int) ([]topEntry, float64, error) {
+	n = min(max(n, 1), maxTopN)
+	query := fmt.Sprintf(`query ($zoneTag: string!, $filter: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      total: httpRequestsAdaptiveGroups(filter: $filter, limit: 1) { count avg { sampleInterval } }
+      top: httpRequestsAdaptiveGroups(filter: $filter, limit: %d, orderBy: [count_DESC]) {
+        count
+        avg { sampleInterval }
+        dimensions { %s }
+      }
+    }
+  }
+}`, n, dimension)
+
+	z, err := queryZone[struct {
+		Total []struct {
+			sampledGroup
+			Count float64 `json:"count"`
+		} `json:"total"`
+		Top []struct {
+			sampledGroup
+			Count      float64                    `json:"count"`
+			Dimensions map[string]json.RawMessage `json:"dimensions"`
+		} `json:"top"`
+	}](zone, query, map[string]any{"filter": zoneFilter(zone, "httpRequestsAdaptiveGroups", adaptiveFilter())})
+	if err != nil || z == nil {
+		return nil, 0, err
+	}
+
+	total := 0.0
+	for _, g := range z.Total {
+		total += sampledCount(g.Count, g.sampledGroup)
+	}
+	entries := make([]topEntry, 0, len(z.Top))
+	for _, g := range z.Top {
+		var value string
+		if err := json.Unmarshal(g.Dimensions[dimension], &value); err != nil {
+			value = strings.Trim(string(g.Dimensions[dimension]), `"`)
+		}
+		entries = append(entries, topEntry{Value: value, Count: sampledCount(g.Count, g.sampledGroup)})
+	}
+	return entries, total, nil
+}
+
+// fetchTopN sets metric{zone_tag, <value>} for the top n values of
+// dimension, plus an "__other__" series with the remainder. normalize, if
+// set, maps raw values to the label value; entries mapping to the same
+// label are summed.
+func fetchTopN(zone Zone, dimension string, n int, normalize func(string) string, metric *prometheus.GaugeVec) error {
+	entries, total, err := queryTopN(zone, dimension, n)
+	if err != nil {
+		return err
+	}
+
+	metric.DeletePartialMatch(prometheus.Labels{"zone_tag": zone.Tag})
+	if len(entries) == 0 {
+		return nil
+	}
+
+	covered := 0.0
+	values := map[string]float64{}
+	for _, e := range entries {
+		value := e.Value
+		if normalize != nil {
+			value = normalize(value)
+		}
+		value = truncateLabel(value)
+		values[value] += e.Count
+		covered += e.Count
+	}
+	for value, count := range values {
+		metric.WithLabelValues(zone.Tag, value).Set(count)
+	}
+	metric.WithLabelValues(zone.Tag, topOtherLabel).Set(max(0, total-covered))
+	return nil
+}
+
+// truncateLabel makes a dimension value safe as a label value: invalid
+// UTF-8 is replaced and the value is cut to maxTopLabelLength bytes on a
+// rune boundary, since WithLabelValues panics on broken UTF-8.
+func truncateLabel(value string) string {
+	value = strings.ToValidUTF8(value, "\uFFFD")
+	if len(value) <= maxTopLabelLength {
+		return value
+	}
+	n := maxTopLabelLength
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return value[:n]
+}
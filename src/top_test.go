@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateLabel(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"short", "/index.html", "/index.html"},
+		{"at limit", strings.Repeat("a", maxTopLabelLength), strings.Repeat("a", maxTopLabelLength)},
+		{"ascii over limit", strings.Repeat("a", maxTopLabelLength+10), strings.Repeat("a", maxTopLabelLength)},
+		// the 65th two-byte rune would straddle the limit
+		{"two-byte runes", strings.Repeat("я", maxTopLabelLength/2+1), strings.Repeat("я", maxTopLabelLength/2)},
+		{"rune across the limit", strings.Repeat("a", maxTopLabelLength-1) + "я", strings.Repeat("a", maxTopLabelLength-1)},
+		{"four-byte rune across the limit", strings.Repeat("a", maxTopLabelLength-2) + "😀", strings.Repeat("a", maxTopLabelLength-2)},
+		{"invalid utf-8", "/a\xffb", "/a�b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateLabel(tt.value)
+			if got != tt.want {
+				t.Errorf("truncateLabel(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+			if len(got) > maxTopLabelLength || !utf8.ValidString(got) {
+				t.Errorf("truncateLabel(%q) = %q is not a valid label value", tt.value, got)
+			}
+		})
+	}
+}
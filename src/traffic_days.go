@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// finalAfterDays is the age in days after which a date's 1dGroups totals
+// no longer change; such dates are fetched once and then left alone.
+const finalAfterDays = 2
+
+// trafficDates tracks, per zone, the dates exported by the traffic
+// collector and whether they were final when fetched.
+var (
+	trafficDates      = map[string]map[string]bool{}
+	trafficDatesMutex = &sync.Mutex{}
+)
+
+// dailyTrafficMetrics are the traffic collector's vectors with a date label.
+func dailyTrafficMetrics() []*prometheus.GaugeVec {
+	return []*prometheus.GaugeVec{
+		reqMetric, pageViews, uniquesMetric, cachedMetric, bandwidthMetric, cachedBandwidthMetric,
+		originRequestsMetric, originBandwidthMetric, originOffloadMetric,
+		cacheHitRatioMetric, cacheHitBytesRatioMetric,
+		byStatusMetric, statusClassMetric, threatsMetric, byCountryMetric,
+	}
+}
+
+// lookbackDates lists the LOOKBACK_DAYS dates ending today, newest first.
+func lookbackDates() []string {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	dates := []string{}
+	for i := 0; i < max(lookbackDays, 1); i++ {
+		dates = append(dates, today.AddDate(0, 0, -i).Format("2006-01-02"))
+	}
+	return dates
+}
+
+func dateFinal(date string) bool {
+	cutoff := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -finalAfterDays)
+	return date <= cutoff.Format("2006-01-02")
+}
+
+// trafficFetchDays returns how many dates, counting back from today, have
+// to be queried: up to the oldest one not exported as final yet.
+func trafficFetchDays(zone Zone, dates []string) int {
+	trafficDatesMutex.Lock()
+	defer trafficDatesMutex.Unlock()
+	days := 0
+	for i, date := range dates {
+		if !trafficDates[zone.Tag][date] {
+			days = i + 1
+		}
+	}
+	return days
+}
+
+// markTrafficDate records an exported date.
+func markTrafficDate(zone Zone, date string) {
+	trafficDatesMutex.Lock()
+	defer trafficDatesMutex.Unlock()
+	if trafficDates[zone.Tag] == nil {
+		trafficDates[zone.Tag] = map[string]bool{}
+	}
+	trafficDates[zone.Tag][date] = dateFinal(date)
+}
+
+// expireTrafficDates drops the series of dates that left the lookback
+// window.
+func expireTrafficDates(zone Zone, dates []string) {
+	keep := map[string]bool{}
+	for _, date := range dates {
+		keep[date] = true
+	}
+	trafficDatesMutex.Lock()
+	defer trafficDatesMutex.Unlock()
+	for date := range trafficDates[zone.Tag] {
+		if keep[date] {
+			continue
+		}
+		labels := prometheus.Labels{"zone_tag": zone.Tag, "date": date}
+		for _, m := range dailyTrafficMetrics() {
+			m.DeletePartialMatch(labels)
+		}
+		delete(trafficDates[zone.Tag], date)
+	}
+}
+
+// forgetTrafficDates is called when the zone's series were removed.
+func forgetTrafficDates(zoneTag string) {
+	trafficDatesMutex.Lock()
+	defer trafficDatesMutex.Unlock()
+	delete(trafficDates, zoneTag)
+}
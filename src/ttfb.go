@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	edgeTTFBMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_edge_ttfb_seconds",
+			Help: "Edge time to first byte quantiles per status class over the adaptive window",
+		},
+		[]string{"zone_tag", "status_class", "quantile"},
+	)
+
+	edgeTTFBRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_edge_ttfb_requests",
+			Help: "Requests the TTFB quantiles of a status class are based on",
+		},
+		[]string{"zone_tag", "status_class"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(edgeTTFBMetric)
+	cycleRegistry.MustRegister(edgeTTFBRequests)
+
+	registerCollector(collector{
+		name:     "ttfb",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequestsAdaptiveGroups"},
+		zone:     fetchTTFB,
+	})
+}
+
+// quantiles can't be merged across status codes, so every class is its own
+// filtered group
+const ttfbQuery = `query ($zoneTag: string!, $c2xx: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!, $c3xx: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!, $c4xx: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!, $c5xx: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      c2xx: httpRequestsAdaptiveGroups(filter: $c2xx, limit: 1) { ...ttfb }
+      c3xx: httpRequestsAdaptiveGroups(filter: $c3xx, limit: 1) { ...ttfb }
+      c4xx: httpRequestsAdaptiveGroups(filter: $c4xx, limit: 1) { ...ttfb }
+      c5xx: httpRequestsAdaptiveGroups(filter: $c5xx, limit: 1) { ...ttfb }
+    }
+  }
+}
+
+fragment ttfb on ZoneHttpRequestsAdaptiveGroups {
+  count
+  quantiles { edgeTimeToFirstByteMsP50 edgeTimeToFirstByteMsP95 edgeTimeToFirstByteMsP99 }
+}`
+
+type ttfbGroup struct {
+	Count     float64 `json:"count"`
+	Quantiles struct {
+		P50 float64 `json:"edgeTimeToFirstByteMsP50"`
+		P95 float64 `json:"edgeTimeToFirstByteMsP95"`
+		P99 float64 `json:"edgeTimeToFirstByteMsP99"`
+	} `json:"quantiles"`
+}
+
+func fetchTTFB(zone Zone) error {
+	classFilter := func(from int) map[string]any {
+		filter := adaptiveFilter()
+		filter["edgeResponseStatus_geq"] = from
+		filter["edgeResponseStatus_lt"] = from + 100
+		return zoneFilter(zone, "httpRequestsAdaptiveGroups", filter)
+	}
+	z, err := queryZone[struct {
+		C2xx []ttfbGroup `json:"c2xx"`
+		C3xx []ttfbGroup `json:"c3xx"`
+		C4xx []ttfbGroup `json:"c4xx"`
+		C5xx []ttfbGroup `json:"c5xx"`
+	}](zone, ttfbQuery, map[string]any{
+		"c2xx": classFilter(200),
+		"c3xx": classFilter(300),
+		"c4xx": classFilter(400),
+		"c5xx": classFilter(500),
+	})
+	if err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	edgeTTFBMetric.DeletePartialMatch(byZone)
+	edgeTTFBRequests.DeletePartialMatch(byZone)
+	if z == nil {
+		return nil
+	}
+
+	for class, groups := range map[string][]ttfbGroup{"2xx": z.C2xx, "3xx": z.C3xx, "4xx": z.C4xx, "5xx": z.C5xx} {
+		if len(groups) == 0 || groups[0].Count == 0 {
+			edgeTTFBRequests.WithLabelValues(zone.Tag, class).Set(0)
+			continue
+		}
+		g := groups[0]
+		edgeTTFBRequests.WithLabelValues(zone.Tag, class).Set(g.Count)
+		edgeTTFBMetric.WithLabelValues(zone.Tag, class, "0.5").Set(g.Quantiles.P50 / 1000)
+		edgeTTFBMetric.WithLabelValues(zone.Tag, class, "0.95").Set(g.Quantiles.P95 / 1000)
+		edgeTTFBMetric.WithLabelValues(zone.Tag, class, "0.99").Set(g.Quantiles.P99 / 1000)
+	}
+	return nil
+}
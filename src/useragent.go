@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+type userAgentRule struct {
+	group   string
+	pattern *regexp.Regexp
+}
+
+var (
+	userAgentGroups = ""
+	userAgentRules  = []userAgentRule{}
+
+	// browser tokens checked in order, e.g. Edge user agents also carry
+	// Chrome and Safari tokens
+	userAgentBrowsers = []struct{ token, family string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"YaBrowser/", "Yandex"},
+		{"SamsungBrowser/", "Samsung"},
+		{"Firefox/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Version/", "Safari"},
+	}
+)
+
+// setUserAgentRules parses USER_AGENT_GROUPS: semicolon-separated
+// group=regexp pairs, checked in order before the built-in normalization.
+func setUserAgentRules(spec string) {
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		group, expr, ok := strings.Cut(item, "=")
+		if !ok {
+			log.Println("[!] Неверное правило USER_AGENT_GROUPS:", item)
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			log.Printf("[!] Неверное правило USER_AGENT_GROUPS %q: %v", item, err)
+			continue
+		}
+		userAgentRules = append(userAgentRules, userAgentRule{group: strings.TrimSpace(group), pattern: re})
+	}
+}
+
+// normalizeUserAgent reduces a user agent to "family/major", keeping SDK
+// and browser major versions apart while dropping everything else.
+func normalizeUserAgent(ua string) string {
+	for _, rule := range userAgentRules {
+		if rule.pattern.MatchString(ua) {
+			return rule.group
+		}
+	}
+	if ua == "" {
+		return "empty"
+	}
+	for _, b := range userAgentBrowsers {
+		if i := strings.Index(ua, b.token); i >= 0 {
+			return b.family + "/" + majorVersion(ua[i+len(b.token):])
+		}
+	}
+	token, _, _ := strings.Cut(ua, " ")
+	name, version, ok := strings.Cut(token, "/")
+	if !ok {
+		return name
+	}
+	return name + "/" + majorVersion(version)
+}
+
+func majorVersion(v string) string {
+	end := strings.IndexFunc(v, func(r rune) bool { return r < '0' || r > '9' })
+	if end < 0 {
+		return v
+	}
+	return v[:end]
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	vectorizeVectors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_vectorize_index_vectors",
+			Help: "Vectors stored in a Vectorize index",
+		},
+		[]string{"account", "index"},
+	)
+
+	vectorizeDimensions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_vectorize_index_dimensions",
+			Help: "Dimensions of the vectors in a Vectorize index",
+		},
+		[]string{"account", "index"},
+	)
+
+	vectorizeQueries = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_vectorize_queries",
+			Help: "Queries against a Vectorize index over the adaptive window",
+		},
+		[]string{"account", "index"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(vectorizeVectors)
+	cycleRegistry.MustRegister(vectorizeDimensions)
+	cycleRegistry.MustRegister(vectorizeQueries)
+
+	registerCollector(collector{
+		name:     "vectorize",
+		scope:    "Vectorize",
+		datasets: []string{"vectorizeV2QueriesAdaptiveGroups"},
+		account:  fetchVectorize,
+	})
+}
+
+const vectorizeQuery = `query ($accountTag: string!, $filter: AccountVectorizeV2QueriesAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    accounts(filter: { accountTag: $accountTag }) {
+      vectorizeV2QueriesAdaptiveGroups(filter: $filter, limit: 1000) {
+        count
+        dimensions { indexName }
+      }
+    }
+  }
+}`
+
+func fetchVectorize(account Account) error {
+	var indexes []struct {
+		Name string `json:"name"`
+	}
+	if _, err := cfGet("/accounts/"+account.ID+"/vectorize/v2/indexes", &indexes); err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	vectorizeVectors.DeletePartialMatch(byAccount)
+	vectorizeDimensions.DeletePartialMatch(byAccount)
+
+	for _, index := range indexes {
+		var info struct {
+			Dimensions  float64 `json:"dimensions"`
+			VectorCount float64 `json:"vectorCount"`
+		}
+		if _, err := cfGet("/accounts/"+account.ID+"/vectorize/v2/indexes/"+url.PathEscape(index.Name)+"/info", &info); err != nil {
+			return err
+		}
+		vectorizeVectors.WithLabelValues(account.Name, index.Name).Set(info.VectorCount)
+		vectorizeDimensions.WithLabelValues(account.Name, index.Name).Set(info.Dimensions)
+	}
+
+	a, err := queryAccount[struct {
+		VectorizeV2QueriesAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				IndexName string `json:"indexName"`
+			} `json:"dimensions"`
+		} `json:"vectorizeV2QueriesAdaptiveGroups"`
+	}](account, vectorizeQuery, map[string]any{"filter": adaptiveFilter()})
+	if err != nil {
+		return err
+	}
+
+	vectorizeQueries.DeletePartialMatch(byAccount)
+	if a == nil {
+		return nil
+	}
+	for _, g := range a.VectorizeV2QueriesAdaptiveGroups {
+		vectorizeQueries.WithLabelValues(account.Name, g.Dimensions.IndexName).Set(g.Count)
+	}
+	return nil
+}
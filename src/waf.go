@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	managedRulesetInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_managed_ruleset_info",
+			Help: "Managed WAF rulesets deployed on the zone with deployed and latest version",
+		},
+		[]string{"zone_tag", "ruleset_id", "name", "deployed_version", "latest_version", "enabled"},
+	)
+
+	managedRulesetOverrides = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_managed_ruleset_overrides",
+			Help: "Overrides configured for a deployed managed ruleset by type",
+		},
+		[]string{"zone_tag", "ruleset_id", "type"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(managedRulesetInfo)
+	cycleRegistry.MustRegister(managedRulesetOverrides)
+
+	registerCollector(collector{
+		name:  "waf_managed",
+		scope: "Zone WAF",
+		zone:  fetchManagedRulesets,
+	})
+}
+
+func fetchManagedRulesets(zone Zone) error {
+	var rulesets []struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Kind    string `json:"kind"`
+		Version string `json:"version"`
+	}
+	if _, err := cfGet("/zones/"+zone.ID+"/rulesets", &rulesets); err != nil {
+		return err
+	}
+	latest := map[string]string{}
+	names := map[string]string{}
+	for _, rs := range rulesets {
+		if rs.Kind == "managed" {
+			latest[rs.ID] = rs.Version
+			names[rs.ID] = rs.Name
+		}
+	}
+
+	var entrypoint struct {
+		Rules []struct {
+			Action           string `json:"action"`
+			Enabled          bool   `json:"enabled"`
+			ActionParameters struct {
+				ID        string `json:"id"`
+				Version   string `json:"version"`
+				Overrides struct {
+					Rules      []struct{} `json:"rules"`
+					Categories []struct{} `json:"categories"`
+				} `json:"overrides"`
+			} `json:"action_parameters"`
+		} `json:"rules"`
+	}
+	_, err := cfGet("/zones/"+zone.ID+"/rulesets/phases/http_request_firewall_managed/entrypoint", &entrypoint)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	managedRulesetInfo.DeletePartialMatch(byZone)
+	managedRulesetOverrides.DeletePartialMatch(byZone)
+
+	for _, rule := range entrypoint.Rules {
+		if rule.Action != "execute" {
+			continue
+		}
+		id := rule.ActionParameters.ID
+		deployed := rule.ActionParameters.Version
+		if deployed == "" {
+			deployed = "latest"
+		}
+		managedRulesetInfo.WithLabelValues(zone.Tag, id, names[id], deployed, latest[id], boolToString(rule.Enabled)).Set(1)
+		managedRulesetOverrides.WithLabelValues(zone.Tag, id, "rules").Set(float64(len(rule.ActionParameters.Overrides.Rules)))
+		managedRulesetOverrides.WithLabelValues(zone.Tag, id, "categories").Set(float64(len(rule.ActionParameters.Overrides.Categories)))
+	}
+	return nil
+}
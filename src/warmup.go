@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// warmupPrometheusURL is the Prometheus that scrapes this exporter;
+	// when set, the last known series are served until the first cycle.
+	warmupPrometheusURL = ""
+	warmupSelector      = `{job="cloudflare", __name__=~"cloudflare_.+"}`
+	// warmupMaxAge is passed as lookback_delta, older samples are ignored.
+	warmupMaxAge = time.Hour
+
+	descNamePattern = regexp.MustCompile(`fqName: "([^"]+)"`)
+)
+
+// warmupDropLabels are added by Prometheus or by the exposing gatherers.
+var warmupDropLabels = map[string]bool{"__name__": true, "job": true, "instance": true}
+
+// cycleMetricNames returns the names of the metrics written by collectors.
+func cycleMetricNames() map[string]bool {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		for _, c := range cycleRegistry.vecs {
+			c.Describe(ch)
+		}
+		close(ch)
+	}()
+	names := map[string]bool{}
+	for desc := range ch {
+		if m := descNamePattern.FindStringSubmatch(desc.String()); m != nil {
+			names[m[1]] = true
+		}
+	}
+	return names
+}
+
+// warmUp queries Prometheus for the exporter's last collector series and
+// exposes them as the cycle snapshot, replaced by the first real cycle.
+func warmUp() error {
+	if warmupPrometheusURL == "" {
+		return nil
+	}
+	q := url.Values{}
+	q.Set("query", warmupSelector)
+	q.Set("lookback_delta", warmupMaxAge.String())
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Get(warmupPrometheusURL + "/api/v1/query?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Value  [2]any            `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("prometheus: status %d: %w", resp.StatusCode, err)
+	}
+	if body.Status != "success" {
+		return fmt.Errorf("prometheus: %s", body.Error)
+	}
+	if body.Data.ResultType != "vector" {
+		return fmt.Errorf("prometheus: %s result, a vector selector is expected", body.Data.ResultType)
+	}
+
+	known := cycleMetricNames()
+	byName := map[string]*dto.MetricFamily{}
+	series := 0
+	for _, sample := range body.Data.Result {
+		name := sample.Metric["__name__"]
+		raw, _ := sample.Value[1].(string)
+		value, err := strconv.ParseFloat(raw, 64)
+		if !known[name] || err != nil {
+			continue
+		}
+		mf, ok := byName[name]
+		if !ok {
+			mf = &dto.MetricFamily{
+				Name: proto.String(name),
+				Help: proto.String("Restored from Prometheus until the first collection cycle"),
+				Type: dto.MetricType_GAUGE.Enum(),
+			}
+			byName[name] = mf
+		}
+		m := &dto.Metric{Gauge: &dto.Gauge{Value: proto.Float64(value)}}
+		for k, v := range sample.Metric {
+			if !warmupDropLabels[k] {
+				m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(k), Value: proto.String(v)})
+			}
+		}
+		sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+		mf.Metric = append(mf.Metric, m)
+		series++
+	}
+
+	families := make([]*dto.MetricFamily, 0, len(byName))
+	for _, mf := range byName {
+		families = append(families, mf)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	// a cycle that finished meanwhile wins
+	cycleSnapshot.CompareAndSwap(nil, &families)
+	log.Println("[OK] Warm-up from Prometheus:", series, "series")
+	return nil
+}
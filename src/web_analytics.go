@@ -0,0 +1,186 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	rumPageloads = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_web_analytics_pageloads",
+			Help: "Web Analytics page loads per site over the adaptive window",
+		},
+		[]string{"account", "site"},
+	)
+
+	rumVisits = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_web_analytics_visits",
+			Help: "Web Analytics visits per site over the adaptive window",
+		},
+		[]string{"account", "site"},
+	)
+
+	rumPageLoadTime = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_web_analytics_page_load_time_seconds",
+			Help: "Web Analytics page load time percentiles per site over the adaptive window",
+		},
+		[]string{"account", "site", "quantile"},
+	)
+
+	rumLCP = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_web_analytics_lcp_seconds",
+			Help: "Largest Contentful Paint p75 per site over the adaptive window",
+		},
+		[]string{"account", "site"},
+	)
+
+	rumINP = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_web_analytics_inp_seconds",
+			Help: "Interaction to Next Paint p75 per site over the adaptive window",
+		},
+		[]string{"account", "site"},
+	)
+
+	rumCLS = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_web_analytics_cls",
+			Help: "Cumulative Layout Shift p75 per site over the adaptive window",
+		},
+		[]string{"account", "site"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(rumPageloads)
+	cycleRegistry.MustRegister(rumVisits)
+	cycleRegistry.MustRegister(rumPageLoadTime)
+	cycleRegistry.MustRegister(rumLCP)
+	cycleRegistry.MustRegister(rumINP)
+	cycleRegistry.MustRegister(rumCLS)
+
+	registerCollector(collector{
+		name:     "web_analytics",
+		scope:    "Account Analytics",
+		datasets: []string{"rumPageloadEventsAdaptiveGroups", "rumPerformanceEventsAdaptiveGroups", "rumWebVitalsEventsAdaptiveGroups"},
+		account:  fetchWebAnalytics,
+	})
+}
+
+// RUM timings are reported in microseconds.
+const rumQuery = `query ($accountTag: string!, $pageloadFilter: AccountRumPageloadEventsAdaptiveGroupsFilter_InputObject!, $performanceFilter: AccountRumPerformanceEventsAdaptiveGroupsFilter_InputObject!, $vitalsFilter: AccountRumWebVitalsEventsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    accounts(filter: { accountTag: $accountTag }) {
+      rumPageloadEventsAdaptiveGroups(filter: $pageloadFilter, limit: 1000) {
+        count
+        sum { visits }
+        dimensions { siteTag }
+      }
+      rumPerformanceEventsAdaptiveGroups(filter: $performanceFilter, limit: 1000) {
+        quantiles { pageLoadTimeP50 pageLoadTimeP75 pageLoadTimeP90 pageLoadTimeP99 }
+        dimensions { siteTag }
+      }
+      rumWebVitalsEventsAdaptiveGroups(filter: $vitalsFilter, limit: 1000) {
+        quantiles { largestContentfulPaintP75 interactionToNextPaintP75 cumulativeLayoutShiftP75 }
+        dimensions { siteTag }
+      }
+    }
+  }
+}`
+
+func fetchWebAnalytics(account Account) error {
+	var sites []struct {
+		SiteTag string `json:"site_tag"`
+		Host    string `json:"host"`
+		Ruleset struct {
+			ZoneName string `json:"zone_name"`
+		} `json:"ruleset"`
+	}
+	if _, err := cfGet("/accounts/"+account.ID+"/rum/site_info/list?per_page=100", &sites); err != nil {
+		return err
+	}
+	names := map[string]string{}
+	for _, s := range sites {
+		switch {
+		case s.Host != "":
+			names[s.SiteTag] = s.Host
+		case s.Ruleset.ZoneName != "":
+			names[s.SiteTag] = s.Ruleset.ZoneName
+		}
+	}
+	siteName := func(tag string) string {
+		if name := names[tag]; name != "" {
+			return name
+		}
+		return tag
+	}
+
+	type siteDimensions struct {
+		SiteTag string `json:"siteTag"`
+	}
+	a, err := queryAccount[struct {
+		RumPageloadEventsAdaptiveGroups []struct {
+			Count float64 `json:"count"`
+			Sum   struct {
+				Visits float64 `json:"visits"`
+			} `json:"sum"`
+			Dimensions siteDimensions `json:"dimensions"`
+		} `json:"rumPageloadEventsAdaptiveGroups"`
+		RumPerformanceEventsAdaptiveGroups []struct {
+			Quantiles struct {
+				PageLoadTimeP50 float64 `json:"pageLoadTimeP50"`
+				PageLoadTimeP75 float64 `json:"pageLoadTimeP75"`
+				PageLoadTimeP90 float64 `json:"pageLoadTimeP90"`
+				PageLoadTimeP99 float64 `json:"pageLoadTimeP99"`
+			} `json:"quantiles"`
+			Dimensions siteDimensions `json:"dimensions"`
+		} `json:"rumPerformanceEventsAdaptiveGroups"`
+		RumWebVitalsEventsAdaptiveGroups []struct {
+			Quantiles struct {
+				LargestContentfulPaintP75 float64 `json:"largestContentfulPaintP75"`
+				InteractionToNextPaintP75 float64 `json:"interactionToNextPaintP75"`
+				CumulativeLayoutShiftP75  float64 `json:"cumulativeLayoutShiftP75"`
+			} `json:"quantiles"`
+			Dimensions siteDimensions `json:"dimensions"`
+		} `json:"rumWebVitalsEventsAdaptiveGroups"`
+	}](account, rumQuery, map[string]any{
+		"pageloadFilter":    adaptiveFilter(),
+		"performanceFilter": adaptiveFilter(),
+		"vitalsFilter":      adaptiveFilter(),
+	})
+	if err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	for _, m := range []*prometheus.GaugeVec{rumPageloads, rumVisits, rumPageLoadTime, rumLCP, rumINP, rumCLS} {
+		m.DeletePartialMatch(byAccount)
+	}
+	if a == nil {
+		return nil
+	}
+
+	for _, g := range a.RumPageloadEventsAdaptiveGroups {
+		site := siteName(g.Dimensions.SiteTag)
+		rumPageloads.WithLabelValues(account.Name, site).Set(g.Count)
+		rumVisits.WithLabelValues(account.Name, site).Set(g.Sum.Visits)
+	}
+	for _, g := range a.RumPerformanceEventsAdaptiveGroups {
+		site := siteName(g.Dimensions.SiteTag)
+		q := g.Quantiles
+		rumPageLoadTime.WithLabelValues(account.Name, site, "0.5").Set(q.PageLoadTimeP50 / 1e6)
+		rumPageLoadTime.WithLabelValues(account.Name, site, "0.75").Set(q.PageLoadTimeP75 / 1e6)
+		rumPageLoadTime.WithLabelValues(account.Name, site, "0.9").Set(q.PageLoadTimeP90 / 1e6)
+		rumPageLoadTime.WithLabelValues(account.Name, site, "0.99").Set(q.PageLoadTimeP99 / 1e6)
+	}
+	for _, g := range a.RumWebVitalsEventsAdaptiveGroups {
+		site := siteName(g.Dimensions.SiteTag)
+		rumLCP.WithLabelValues(account.Name, site).Set(g.Quantiles.LargestContentfulPaintP75 / 1e6)
+		rumINP.WithLabelValues(account.Name, site).Set(g.Quantiles.InteractionToNextPaintP75 / 1e6)
+		rumCLS.WithLabelValues(account.Name, site).Set(g.Quantiles.CumulativeLayoutShiftP75)
+	}
+	return nil
+}
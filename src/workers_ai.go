@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	workersAIRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_workers_ai_requests",
+			Help: "Workers AI inference requests per model over the adaptive window",
+		},
+		[]string{"account", "model"},
+	)
+
+	workersAIErrors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_workers_ai_errors",
+			Help: "Failed Workers AI inference requests per model and error code over the adaptive window",
+		},
+		[]string{"account", "model", "error_code"},
+	)
+
+	workersAINeurons = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_workers_ai_neurons",
+			Help: "Neurons (billed compute) consumed per model over the adaptive window",
+		},
+		[]string{"account", "model"},
+	)
+
+	workersAITokens = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_workers_ai_tokens",
+			Help: "Tokens processed per model by direction over the adaptive window",
+		},
+		[]string{"account", "model", "direction"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(workersAIRequests)
+	cycleRegistry.MustRegister(workersAIErrors)
+	cycleRegistry.MustRegister(workersAINeurons)
+	cycleRegistry.MustRegister(workersAITokens)
+
+	registerCollector(collector{
+		name:     "workers_ai",
+		scope:    "Account Analytics",
+		datasets: []string{"aiInferenceAdaptiveGroups"},
+		account:  fetchWorkersAI,
+	})
+}
+
+const workersAIQuery = `query ($accountTag: string!, $filter: AccountAiInferenceAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    accounts(filter: { accountTag: $accountTag }) {
+      aiInferenceAdaptiveGroups(filter: $filter, limit: 1000) {
+        count
+        sum { totalNeurons totalInputTokens totalOutputTokens }
+        dimensions { modelId errorCode }
+      }
+    }
+  }
+}`
+
+func fetchWorkersAI(account Account) error {
+	a, err := queryAccount[struct {
+		AiInferenceAdaptiveGroups []struct {
+			Count float64 `json:"count"`
+			Sum   struct {
+				TotalNeurons      float64 `json:"totalNeurons"`
+				TotalInputTokens  float64 `json:"totalInputTokens"`
+				TotalOutputTokens float64 `json:"totalOutputTokens"`
+			} `json:"sum"`
+			Dimensions struct {
+				ModelID   string `json:"modelId"`
+				ErrorCode int    `json:"errorCode"`
+			} `json:"dimensions"`
+		} `json:"aiInferenceAdaptiveGroups"`
+	}](account, workersAIQuery, map[string]any{"filter": adaptiveFilter()})
+	if err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	workersAIRequests.DeletePartialMatch(byAccount)
+	workersAIErrors.DeletePartialMatch(byAccount)
+	workersAINeurons.DeletePartialMatch(byAccount)
+	workersAITokens.DeletePartialMatch(byAccount)
+	if a == nil {
+		return nil
+	}
+
+	// groups are split by error code, 0 means success
+	for _, g := range a.AiInferenceAdaptiveGroups {
+		model := g.Dimensions.ModelID
+		workersAIRequests.WithLabelValues(account.Name, model).Add(g.Count)
+		workersAINeurons.WithLabelValues(account.Name, model).Add(g.Sum.TotalNeurons)
+		workersAITokens.WithLabelValues(account.Name, model, "input").Add(g.Sum.TotalInputTokens)
+		workersAITokens.WithLabelValues(account.Name, model, "output").Add(g.Sum.TotalOutputTokens)
+		if g.Dimensions.ErrorCode != 0 {
+			workersAIErrors.WithLabelValues(account.Name, model, strconv.Itoa(g.Dimensions.ErrorCode)).Add(g.Count)
+		}
+	}
+	return nil
+}
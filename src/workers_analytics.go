@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	workerRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_worker_requests",
+			Help: "Worker invocations per script and status over the adaptive window",
+		},
+		[]string{"account", "script_name", "status"},
+	)
+
+	workerErrors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_worker_errors",
+			Help: "Failed Worker invocations per script and status over the adaptive window",
+		},
+		[]string{"account", "script_name", "status"},
+	)
+
+	workerCPUTime = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_worker_cpu_time_seconds",
+			Help: "CPU time percentiles of Worker invocations per script and status over the adaptive window",
+		},
+		[]string{"account", "script_name", "status", "quantile"},
+	)
+
+	workerDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_worker_duration_seconds",
+			Help: "Wall-clock duration percentiles of Worker invocations per script and status over the adaptive window",
+		},
+		[]string{"account", "script_name", "status", "quantile"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(workerRequests)
+	cycleRegistry.MustRegister(workerErrors)
+	cycleRegistry.MustRegister(workerCPUTime)
+	cycleRegistry.MustRegister(workerDuration)
+
+	registerCollector(collector{
+		name:     "workers",
+		scope:    "Account Analytics",
+		datasets: []string{"workersInvocationsAdaptive"},
+		account:  fetchWorkers,
+	})
+}
+
+// cpuTime and wallTime are reported in microseconds
+const workersQuery = `query ($accountTag: string!, $filter: AccountWorkersInvocationsAdaptiveFilter_InputObject!) {
+  viewer {
+    accounts(filter: { accountTag: $accountTag }) {
+      workersInvocationsAdaptive(filter: $filter, limit: 10000) {
+        sum { requests errors }
+        quantiles { cpuTimeP50 cpuTimeP99 wallTimeP50 wallTimeP99 }
+        dimensions { scriptName status }
+      }
+    }
+  }
+}`
+
+func fetchWorkers(account Account) error {
+	a, err := queryAccount[struct {
+		WorkersInvocationsAdaptive []struct {
+			Sum struct {
+				Requests float64 `json:"requests"`
+				Errors   float64 `json:"errors"`
+			} `json:"sum"`
+			Quantiles struct {
+				CPUTimeP50  float64 `json:"cpuTimeP50"`
+				CPUTimeP99  float64 `json:"cpuTimeP99"`
+				WallTimeP50 float64 `json:"wallTimeP50"`
+				WallTimeP99 float64 `json:"wallTimeP99"`
+			} `json:"quantiles"`
+			Dimensions struct {
+				ScriptName string `json:"scriptName"`
+				Status     string `json:"status"`
+			} `json:"dimensions"`
+		} `json:"workersInvocationsAdaptive"`
+	}](account, workersQuery, map[string]any{"filter": adaptiveFilter()})
+	if err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	workerRequests.DeletePartialMatch(byAccount)
+	workerErrors.DeletePartialMatch(byAccount)
+	workerCPUTime.DeletePartialMatch(byAccount)
+	workerDuration.DeletePartialMatch(byAccount)
+	if a == nil {
+		return nil
+	}
+
+	for _, g := range a.WorkersInvocationsAdaptive {
+		script, status := g.Dimensions.ScriptName, g.Dimensions.Status
+		workerRequests.WithLabelValues(account.Name, script, status).Set(g.Sum.Requests)
+		workerErrors.WithLabelValues(account.Name, script, status).Set(g.Sum.Errors)
+		workerCPUTime.WithLabelValues(account.Name, script, status, "0.5").Set(g.Quantiles.CPUTimeP50 / 1e6)
+		workerCPUTime.WithLabelValues(account.Name, script, status, "0.99").Set(g.Quantiles.CPUTimeP99 / 1e6)
+		workerDuration.WithLabelValues(account.Name, script, status, "0.5").Set(g.Quantiles.WallTimeP50 / 1e6)
+		workerDuration.WithLabelValues(account.Name, script, status, "0.99").Set(g.Quantiles.WallTimeP99 / 1e6)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	workersCronInvocations = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_worker_cron_invocations",
+			Help: "Scheduled Worker invocations per script and cron by outcome (success, error, overrun) over the adaptive window",
+		},
+		[]string{"account", "script_name", "cron", "outcome"},
+	)
+
+	workersCronLastSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_worker_cron_last_success_timestamp_seconds",
+			Help: "Last successful scheduled invocation per script and cron, kept until the script is deleted",
+		},
+		[]string{"account", "script_name", "cron"},
+	)
+
+	workersCronLastFailure = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_worker_cron_last_failure_timestamp_seconds",
+			Help: "Last failed or overrun scheduled invocation per script and cron, kept until the script is deleted",
+		},
+		[]string{"account", "script_name", "cron"},
+	)
+
+	// cronLastRuns keeps the last success and failure per account, script
+	// and cron across cycles: an hourly or daily cron has no run in most
+	// adaptive windows.
+	cronLastRuns      = map[string]map[cronKey]*cronLastRun{}
+	cronLastRunsMutex = &sync.Mutex{}
+)
+
+type cronKey struct{ script, cron string }
+
+type cronLastRun struct {
+	success, failure time.Time
+}
+
+func init() {
+	cycleRegistry.MustRegister(workersCronInvocations)
+	cycleRegistry.MustRegister(workersCronLastSuccess)
+	cycleRegistry.MustRegister(workersCronLastFailure)
+
+	registerCollector(collector{
+		name:     "worker_crons",
+		scope:    "Account Analytics",
+		datasets: []string{"workersInvocationsScheduled"},
+		account:  fetchWorkersCron,
+	})
+}
+
+// workersInvocationsScheduled is not aggregated, every row is one run
+const workersCronQuery = `query ($accountTag: string!, $filter: AccountWorkersInvocationsScheduledFilter_InputObject!) {
+  viewer {
+    accounts(filter: { accountTag: $accountTag }) {
+      workersInvocationsScheduled(filter: $filter, limit: 10000, orderBy: [datetime_DESC]) {
+        scriptName
+        cron
+        status
+        datetime
+      }
+    }
+  }
+}`
+
+// cronOutcome maps the invocation status to success, overrun (CPU or
+// memory limit exceeded) or error.
+func cronOutcome(status string) string {
+	switch status {
+	case "success":
+		return "success"
+	case "exceededCpu", "exceededMemory", "exceededResources":
+		return "overrun"
+	}
+	return "error"
+}
+
+func fetchWorkersCron(account Account) error {
+	a, err := queryAccount[struct {
+		WorkersInvocationsScheduled []struct {
+			ScriptName string    `json:"scriptName"`
+			Cron       string    `json:"cron"`
+			Status     string    `json:"status"`
+			Datetime   time.Time `json:"datetime"`
+		} `json:"workersInvocationsScheduled"`
+	}](account, workersCronQuery, map[string]any{"filter": adaptiveFilter()})
+	if err != nil {
+		return err
+	}
+
+	byAccount := prometheus.Labels{"account": account.Name}
+	workersCronInvocations.DeletePartialMatch(byAccount)
+	workersCronLastSuccess.DeletePartialMatch(byAccount)
+	workersCronLastFailure.DeletePartialMatch(byAccount)
+
+	cronLastRunsMutex.Lock()
+	defer cronLastRunsMutex.Unlock()
+	last := cronLastRuns[account.Name]
+	if last == nil {
+		last = map[cronKey]*cronLastRun{}
+		cronLastRuns[account.Name] = last
+	}
+
+	if a != nil {
+		seen := map[cronKey]bool{}
+		for _, run := range a.WorkersInvocationsScheduled {
+			key := cronKey{run.ScriptName, run.Cron}
+			if !seen[key] {
+				// report every outcome so rate alerts don't see absent series
+				seen[key] = true
+				for _, outcome := range []string{"success", "error", "overrun"} {
+					workersCronInvocations.WithLabelValues(account.Name, key.script, key.cron, outcome)
+				}
+			}
+			outcome := cronOutcome(run.Status)
+			workersCronInvocations.WithLabelValues(account.Name, key.script, key.cron, outcome).Inc()
+			if last[key] == nil {
+				last[key] = &cronLastRun{}
+			}
+			if outcome == "success" {
+				if run.Datetime.After(last[key].success) {
+					last[key].success = run.Datetime
+				}
+			} else if run.Datetime.After(last[key].failure) {
+				last[key].failure = run.Datetime
+			}
+		}
+	}
+
+	// forget the runs of deleted scripts; without the list keep them all
+	scripts, listErr := cfGetAll[struct {
+		ID string `json:"id"`
+	}]("/accounts/"+account.ID+"/workers/scripts", 100)
+	if listErr == nil {
+		exists := map[string]bool{}
+		for _, script := range scripts {
+			exists[script.ID] = true
+		}
+		for key := range last {
+			if !exists[key.script] {
+				delete(last, key)
+			}
+		}
+	}
+
+	for key, run := range last {
+		if !run.success.IsZero() {
+			workersCronLastSuccess.WithLabelValues(account.Name, key.script, key.cron).Set(float64(run.success.Unix()))
+		}
+		if !run.failure.IsZero() {
+			workersCronLastFailure.WithLabelValues(account.Name, key.script, key.cron).Set(float64(run.failure.Unix()))
+		}
+	}
+	if listErr != nil {
+		return fmt.Errorf("list worker scripts: %w", listErr)
+	}
+	return nil
+}
+
+// forgetWorkersCron drops the kept runs of a removed account target.
+func forgetWorkersCron(target string) {
+	name, ok := strings.CutPrefix(target, "account:")
+	if !ok {
+		return
+	}
+	cronLastRunsMutex.Lock()
+	defer cronLastRunsMutex.Unlock()
+	delete(cronLastRuns, name)
+}
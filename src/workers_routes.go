@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	workerRoutesMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_worker_routes",
+			Help: "Number of Worker routes per zone",
+		},
+		[]string{"zone_tag"},
+	)
+
+	workerRouteInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_worker_route_info",
+			Help: "Worker routes configured on the zone",
+		},
+		[]string{"zone_tag", "route_id", "pattern", "script"},
+	)
+
+	workerDomainsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_worker_custom_domains",
+			Help: "Number of Worker custom domains per zone",
+		},
+		[]string{"zone_tag"},
+	)
+
+	workerDomainInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_worker_custom_domain_info",
+			Help: "Worker custom domains attached to the zone",
+		},
+		[]string{"zone_tag", "hostname", "service", "environment"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(workerRoutesMetric)
+	cycleRegistry.MustRegister(workerRouteInfo)
+	cycleRegistry.MustRegister(workerDomainsMetric)
+	cycleRegistry.MustRegister(workerDomainInfo)
+
+	registerCollector(collector{
+		name:  "worker_routes",
+		scope: "Workers Routes",
+		zone:  fetchWorkerRoutes,
+	})
+}
+
+func fetchWorkerRoutes(zone Zone) error {
+	var routes []struct {
+		ID      string `json:"id"`
+		Pattern string `json:"pattern"`
+		Script  string `json:"script"`
+	}
+	if _, err := cfGet("/zones/"+zone.ID+"/workers/routes", &routes); err != nil {
+		return err
+	}
+
+	var domains []struct {
+		Hostname    string `json:"hostname"`
+		Service     string `json:"service"`
+		Environment string `json:"environment"`
+	}
+	if _, err := cfGet("/accounts/"+zone.AccountID+"/workers/domains?zone_id="+url.QueryEscape(zone.ID), &domains); err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	workerRouteInfo.DeletePartialMatch(byZone)
+	workerDomainInfo.DeletePartialMatch(byZone)
+
+	workerRoutesMetric.WithLabelValues(zone.Tag).Set(float64(len(routes)))
+	for _, r := range routes {
+		workerRouteInfo.WithLabelValues(zone.Tag, r.ID, r.Pattern, r.Script).Set(1)
+	}
+	workerDomainsMetric.WithLabelValues(zone.Tag).Set(float64(len(domains)))
+	for _, d := range domains {
+		workerDomainInfo.WithLabelValues(zone.Tag, d.Hostname, d.Service, d.Environment).Set(1)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestsWoWRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_requests_wow_ratio",
+			Help: "Requests of the current day so far divided by the same period one week earlier",
+		},
+		[]string{"zone_tag"},
+	)
+
+	bytesWoWRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_bytes_wow_ratio",
+			Help: "Bytes of the current day so far divided by the same period one week earlier",
+		},
+		[]string{"zone_tag"},
+	)
+
+	errorsWoWRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_errors_wow_ratio",
+			Help: "5xx responses of the current day so far divided by the same period one week earlier",
+		},
+		[]string{"zone_tag"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(requestsWoWRatio)
+	cycleRegistry.MustRegister(bytesWoWRatio)
+	cycleRegistry.MustRegister(errorsWoWRatio)
+
+	registerCollector(collector{
+		name:     "wow",
+		scope:    "Zone Analytics",
+		datasets: []string{"httpRequests1hGroups"},
+		zone:     fetchWoW,
+	})
+}
+
+// hourly groups so that a partial current day is compared with the same
+// hours of the previous week, not with a full day
+const wowQuery = `query ($zoneTag: string!, $current: ZoneHttpRequests1hGroupsFilter_InputObject!, $previous: ZoneHttpRequests1hGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      current: httpRequests1hGroups(filter: $current, limit: 48) {
+        sum { requests bytes responseStatusMap { edgeResponseStatus requests } }
+      }
+      previous: httpRequests1hGroups(filter: $previous, limit: 48) {
+        sum { requests bytes responseStatusMap { edgeResponseStatus requests } }
+      }
+    }
+  }
+}`
+
+type wowGroup struct {
+	Sum struct {
+		Requests          float64 `json:"requests"`
+		Bytes             float64 `json:"bytes"`
+		ResponseStatusMap []struct {
+			EdgeResponseStatus int     `json:"edgeResponseStatus"`
+			Requests           float64 `json:"requests"`
+		} `json:"responseStatusMap"`
+	} `json:"sum"`
+}
+
+func sumWoW(groups []wowGroup) (requests, bytes, errors float64) {
+	for _, g := range groups {
+		requests += g.Sum.Requests
+		bytes += g.Sum.Bytes
+		for _, s := range g.Sum.ResponseStatusMap {
+			if s.EdgeResponseStatus >= 500 && s.EdgeResponseStatus <= 599 {
+				errors += s.Requests
+			}
+		}
+	}
+	return requests, bytes, errors
+}
+
+func fetchWoW(zone Zone) error {
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	weekAgo := -7 * 24 * time.Hour
+	window := func(from, to time.Time) map[string]any {
+		return zoneFilter(zone, "httpRequests1hGroups", map[string]any{
+			"datetime_geq": from.Format(time.RFC3339),
+			"datetime_lt":  to.Format(time.RFC3339),
+		})
+	}
+
+	z, err := queryZone[struct {
+		Current  []wowGroup `json:"current"`
+		Previous []wowGroup `json:"previous"`
+	}](zone, wowQuery, map[string]any{
+		"current":  window(dayStart, now),
+		"previous": window(dayStart.Add(weekAgo), now.Add(weekAgo)),
+	})
+	if err != nil || z == nil {
+		return err
+	}
+
+	curRequests, curBytes, curErrors := sumWoW(z.Current)
+	prevRequests, prevBytes, prevErrors := sumWoW(z.Previous)
+	setRatio := func(m *prometheus.GaugeVec, cur, prev float64) {
+		if prev > 0 {
+			m.WithLabelValues(zone.Tag).Set(cur / prev)
+		} else {
+			m.DeleteLabelValues(zone.Tag)
+		}
+	}
+	setRatio(requestsWoWRatio, curRequests, prevRequests)
+	setRatio(bytesWoWRatio, curBytes, prevBytes)
+	setRatio(errorsWoWRatio, curErrors, prevErrors)
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	zarazLoads = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_zaraz_loads",
+			Help: "Zaraz loads (Pageview triggers) over the adaptive window",
+		},
+		[]string{"zone_tag"},
+	)
+
+	zarazTriggers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_zaraz_triggers",
+			Help: "Zaraz triggers fired by trigger name over the adaptive window",
+		},
+		[]string{"zone_tag", "trigger"},
+	)
+
+	zarazToolActions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_zone_zaraz_tool_actions",
+			Help: "Zaraz tool actions executed by tool over the adaptive window",
+		},
+		[]string{"zone_tag", "tool"},
+	)
+)
+
+func init() {
+	cycleRegistry.MustRegister(zarazLoads)
+	cycleRegistry.MustRegister(zarazTriggers)
+	cycleRegistry.MustRegister(zarazToolActions)
+
+	registerCollector(collector{
+		name:     "zaraz",
+		scope:    "Zone Analytics",
+		datasets: []string{"zarazTriggersAdaptiveGroups", "zarazActionsAdaptiveGroups"},
+		zone:     fetchZaraz,
+	})
+}
+
+const zarazQuery = `query ($zoneTag: string!, $triggersFilter: ZoneZarazTriggersAdaptiveGroupsFilter_InputObject!, $actionsFilter: ZoneZarazActionsAdaptiveGroupsFilter_InputObject!) {
+  viewer {
+    zones(filter: { zoneTag: $zoneTag }) {
+      zarazTriggersAdaptiveGroups(filter: $triggersFilter, limit: 1000) {
+        count
+        dimensions { triggerName }
+      }
+      zarazActionsAdaptiveGroups(filter: $actionsFilter, limit: 1000) {
+        count
+        dimensions { toolName }
+      }
+    }
+  }
+}`
+
+func fetchZaraz(zone Zone) error {
+	z, err := queryZone[struct {
+		ZarazTriggersAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				TriggerName string `json:"triggerName"`
+			} `json:"dimensions"`
+		} `json:"zarazTriggersAdaptiveGroups"`
+		ZarazActionsAdaptiveGroups []struct {
+			Count      float64 `json:"count"`
+			Dimensions struct {
+				ToolName string `json:"toolName"`
+			} `json:"dimensions"`
+		} `json:"zarazActionsAdaptiveGroups"`
+	}](zone, zarazQuery, map[string]any{
+		"triggersFilter": zoneFilter(zone, "zarazTriggersAdaptiveGroups", adaptiveFilter()),
+		"actionsFilter":  zoneFilter(zone, "zarazActionsAdaptiveGroups", adaptiveFilter()),
+	})
+	if err != nil {
+		return err
+	}
+
+	byZone := prometheus.Labels{"zone_tag": zone.Tag}
+	zarazLoads.DeletePartialMatch(byZone)
+	zarazTriggers.DeletePartialMatch(byZone)
+	zarazToolActions.DeletePartialMatch(byZone)
+	if z == nil {
+		return nil
+	}
+
+	loads := 0.0
+	for _, g := range z.ZarazTriggersAdaptiveGroups {
+		zarazTriggers.WithLabelValues(zone.Tag, g.Dimensions.TriggerName).Set(g.Count)
+		if g.Dimensions.TriggerName == "Pageview" {
+			loads += g.Count
+		}
+	}
+	zarazLoads.WithLabelValues(zone.Tag).Set(loads)
+	for _, g := range z.ZarazActionsAdaptiveGroups {
+		zarazToolActions.WithLabelValues(zone.Tag, g.Dimensions.ToolName).Set(g.Count)
+	}
+	return nil
+}